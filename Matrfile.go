@@ -152,7 +152,7 @@ func Lint(ctx context.Context, args []string) error {
 
 	if sliceutil.Contains(args, "proto") {
 		fmt.Println("Running Buf Lint...")
-		if err := matr.Sh(`go run github.com/bufbuild/buf/cmd/buf lint`).Run(); err != nil {
+		if err := matr.Sh(`go run github.com/bufbuild/buf/cmd/buf@v1.29.0 lint`).Run(); err != nil {
 			return errors.Wrap(err, "[BUF-LINT ERRORS]")
 		}
 	}
@@ -178,7 +178,7 @@ func Docker(ctx context.Context, args []string) error {
 
 // Proto generates all the protobuf based artifacts
 func Proto(ctx context.Context, args []string) error {
-	if err := matr.Sh(`go run github.com/bufbuild/buf/cmd/buf generate`).Run(); err != nil {
+	if err := matr.Sh(`go run github.com/bufbuild/buf/cmd/buf@v1.29.0 generate`).Run(); err != nil {
 		return errors.Wrap(err, "[PROTO-GEN ERROR]")
 	}
 
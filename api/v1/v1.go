@@ -0,0 +1,43 @@
+// Package apiv1 freezes the JSON shape the next-event endpoints had before the
+// API was versioned: the bare next event, serialized with the Go field names
+// and only the fields types.Event had then. There is no idle state, a request
+// without a next event is answered 404. Deployed Tidbyt apps depend on this
+// shape, so fields here are never added, renamed or removed.
+package apiv1
+
+import t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+
+type Event struct {
+	Name              string  `json:"Name"`
+	StartTime         int64   `json:"StartTime"`
+	EndTime           int64   `json:"EndTime"`
+	Location          *string `json:"Location"`
+	TenMinuteWarning  bool    `json:"TenMinuteWarning"`
+	FiveMinuteWarning bool    `json:"FiveMinuteWarning"`
+	OneMinuteWarning  bool    `json:"OneMinuteWarning"`
+	InProgress        bool    `json:"InProgress"`
+}
+
+// FromNextEvent converts the display state into its v1 shape, nil when there
+// is no next event
+func FromNextEvent(resp t.NextEventResponse) *Event {
+	if resp.Event == nil {
+		return nil
+	}
+	e := FromEvent(*resp.Event)
+	return &e
+}
+
+// FromEvent converts an event into its v1 shape
+func FromEvent(e t.Event) Event {
+	return Event{
+		Name:              e.Name,
+		StartTime:         e.StartTime,
+		EndTime:           e.EndTime,
+		Location:          e.Location,
+		TenMinuteWarning:  e.TenMinuteWarning,
+		FiveMinuteWarning: e.FiveMinuteWarning,
+		OneMinuteWarning:  e.OneMinuteWarning,
+		InProgress:        e.InProgress,
+	}
+}
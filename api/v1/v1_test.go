@@ -0,0 +1,70 @@
+package apiv1
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// event sets every field of an event, so fields the v1 shape must not carry
+// would show up in the output
+func event() types.Event {
+	location, rawLocation := "Room 1", "Room 1 ☕"
+	return types.Event{
+		UID:               "standup@example.com",
+		RecurrenceID:      1700000000,
+		Name:              "Standup",
+		RawName:           "Standup ☕",
+		StartTime:         1700000000,
+		EndTime:           1700001800,
+		Location:          &location,
+		RawLocation:       &rawLocation,
+		Calendar:          "Work",
+		Categories:        []string{"meeting"},
+		Colors:            types.Colors{Source: "#FF0000", Foreground: "#FFFFFF", Background: "#FF0000"},
+		TenMinuteWarning:  true,
+		FiveMinuteWarning: true,
+		SecondsUntilStart: 240,
+		SecondsUntilEnd:   2040,
+		RefreshAt:         1699999820,
+		RefreshInSeconds:  60,
+		Lines:             map[string][]string{"tb-8": {"Standup"}},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	e := event()
+	golden(t, "event", FromNextEvent(types.NextEventResponse{State: types.StateUpcoming, Event: &e, WindowEnd: 1700600000}))
+}
+
+// golden compares the JSON of v with testdata/name.json, a renamed, added or
+// removed field fails until the file is rewritten with -update
+func golden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".json")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s changed, deployed clients depend on it:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
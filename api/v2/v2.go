@@ -0,0 +1,135 @@
+// Package apiv2 is the designed JSON schema of the next-event endpoints and of
+// the payloads pushed to webhooks and MQTT. Every field is camelCase. Fields
+// may be added, but existing ones are never renamed or removed.
+package apiv2
+
+import (
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+type Warning string
+
+const (
+	WarningNone        Warning = "none"
+	WarningTenMinutes  Warning = "ten_minutes"
+	WarningFiveMinutes Warning = "five_minutes"
+	WarningOneMinute   Warning = "one_minute"
+)
+
+type Event struct {
+	ID          string              `json:"id" doc:"Identifies the event across refreshes of its feed"`
+	Name        string              `json:"name" doc:"Name rewritten into glyphs the Tidbyt fonts can draw"`
+	RawName     string              `json:"rawName" doc:"Name as it appears in the feed"`
+	StartTime   int64               `json:"startTime" doc:"Unix time in seconds"`
+	EndTime     int64               `json:"endTime" doc:"Unix time in seconds"`
+	Location    *string             `json:"location"`
+	RawLocation *string             `json:"rawLocation"`
+	Calendar    string              `json:"calendar" doc:"Name of the calendar the event belongs to"`
+	Categories  []string            `json:"categories"`
+	Cancelled   bool                `json:"cancelled"`
+	Colors      Colors              `json:"colors"`
+	Warning     Warning             `json:"warning" enum:"none ten_minutes five_minutes one_minute" doc:"Warning threshold the event is in"`
+	InProgress  bool                `json:"inProgress"`
+	Countdown   Countdown           `json:"countdown"`
+	Lines       map[string][]string `json:"lines" doc:"Name broken into lines for each Tidbyt font"`
+}
+
+type Colors struct {
+	Source     string `json:"source" doc:"Color the feed gave the event, empty when it gave none"`
+	Foreground string `json:"foreground"`
+	Background string `json:"background"`
+}
+
+type Countdown struct {
+	SecondsUntilStart int64   `json:"secondsUntilStart"`
+	SecondsUntilEnd   int64   `json:"secondsUntilEnd"`
+	PercentElapsed    float64 `json:"percentElapsed"`
+	RefreshAt         int64   `json:"refreshAt" doc:"Unix time the display next changes"`
+	RefreshInSeconds  int64   `json:"refreshInSeconds"`
+}
+
+type NextEventResponse struct {
+	State            string `json:"state" enum:"idle upcoming in_progress"`
+	Event            *Event `json:"event"`
+	WindowEnd        int64  `json:"windowEnd"`
+	FilteredOut      int    `json:"filteredOut"`
+	RefreshAt        int64  `json:"refreshAt"`
+	RefreshInSeconds int64  `json:"refreshInSeconds"`
+}
+
+type Transition struct {
+	Kind      string `json:"kind" enum:"threshold started ended rescheduled cancelled"`
+	Threshold int64  `json:"threshold,omitempty" doc:"Seconds before the start that was crossed"`
+	At        int64  `json:"at"`
+	Event     Event  `json:"event"`
+	Previous  *Event `json:"previous,omitempty" doc:"The event before it was rescheduled or cancelled"`
+}
+
+// FromNextEvent converts the display state into its v2 shape
+func FromNextEvent(resp t.NextEventResponse) NextEventResponse {
+	v2 := NextEventResponse{
+		State:            string(resp.State),
+		WindowEnd:        resp.WindowEnd,
+		FilteredOut:      resp.FilteredOut,
+		RefreshAt:        resp.RefreshAt,
+		RefreshInSeconds: resp.RefreshInSeconds,
+	}
+	if resp.Event != nil {
+		e := FromEvent(*resp.Event)
+		v2.Event = &e
+	}
+	return v2
+}
+
+// FromEvent converts an event into its v2 shape
+func FromEvent(e t.Event) Event {
+	categories := e.Categories
+	if categories == nil {
+		categories = []string{}
+	}
+
+	warning := WarningNone
+	switch {
+	case e.OneMinuteWarning:
+		warning = WarningOneMinute
+	case e.FiveMinuteWarning:
+		warning = WarningFiveMinutes
+	case e.TenMinuteWarning:
+		warning = WarningTenMinutes
+	}
+
+	return Event{
+		ID:          c.Key(e),
+		Name:        e.Name,
+		RawName:     e.RawName,
+		StartTime:   e.StartTime,
+		EndTime:     e.EndTime,
+		Location:    e.Location,
+		RawLocation: e.RawLocation,
+		Calendar:    e.Calendar,
+		Categories:  categories,
+		Cancelled:   e.Cancelled,
+		Colors:      Colors{Source: e.Colors.Source, Foreground: e.Colors.Foreground, Background: e.Colors.Background},
+		Warning:     warning,
+		InProgress:  e.InProgress,
+		Countdown: Countdown{
+			SecondsUntilStart: e.SecondsUntilStart,
+			SecondsUntilEnd:   e.SecondsUntilEnd,
+			PercentElapsed:    e.PercentElapsed,
+			RefreshAt:         e.RefreshAt,
+			RefreshInSeconds:  e.RefreshInSeconds,
+		},
+		Lines: e.Lines,
+	}
+}
+
+// FromTransition converts a transition into its v2 shape
+func FromTransition(tr t.Transition) Transition {
+	v2 := Transition{Kind: string(tr.Kind), Threshold: tr.Threshold, At: tr.At, Event: FromEvent(tr.Event)}
+	if tr.Previous != nil {
+		previous := FromEvent(*tr.Previous)
+		v2.Previous = &previous
+	}
+	return v2
+}
@@ -0,0 +1,96 @@
+package apiv2
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// event sets every field of an event
+func event() types.Event {
+	location, rawLocation := "Room 1", "Room 1 ☕"
+	return types.Event{
+		UID:               "standup@example.com",
+		RecurrenceID:      1700000000,
+		Name:              "Standup",
+		RawName:           "Standup ☕",
+		StartTime:         1700000000,
+		EndTime:           1700001800,
+		Location:          &location,
+		RawLocation:       &rawLocation,
+		Calendar:          "Work",
+		Categories:        []string{"meeting"},
+		Colors:            types.Colors{Source: "#FF0000", Foreground: "#FFFFFF", Background: "#FF0000"},
+		TenMinuteWarning:  true,
+		FiveMinuteWarning: true,
+		SecondsUntilStart: 240,
+		SecondsUntilEnd:   2040,
+		RefreshAt:         1699999820,
+		RefreshInSeconds:  60,
+		Lines:             map[string][]string{"tb-8": {"Standup"}},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	e := event()
+	previous := event()
+	previous.StartTime, previous.EndTime = 1699996400, 1699998200
+
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{name: "next_event", v: FromNextEvent(types.NextEventResponse{
+			State:            types.StateUpcoming,
+			Event:            &e,
+			WindowEnd:        1700600000,
+			FilteredOut:      2,
+			RefreshAt:        1699999820,
+			RefreshInSeconds: 60,
+		})},
+		{name: "idle", v: FromNextEvent(types.NextEventResponse{State: types.StateIdle, WindowEnd: 1700600000, FilteredOut: 1})},
+		{name: "transition", v: FromTransition(types.Transition{
+			Kind:     types.TransitionRescheduled,
+			At:       1699990000,
+			Event:    e,
+			Previous: &previous,
+		})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			golden(t, test.name, test.v)
+		})
+	}
+}
+
+// golden compares the JSON of v with testdata/name.json, a renamed or removed
+// field fails. Added fields are rewritten into the file with -update.
+func golden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".json")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s changed, fields may be added but never renamed or removed:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
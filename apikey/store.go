@@ -0,0 +1,228 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/jsonfile"
+)
+
+const (
+	// keyPrefix marks API keys so secret scanners and people can tell them apart
+	keyPrefix = "ics_"
+	// touchInterval limits how often the last use of a key is written to disk
+	touchInterval = time.Minute
+	// racyWindow covers the coarse clock file systems stamp modifications
+	// with, a file modified this shortly before it was read may have changed
+	// again without a new modification time
+	racyWindow = time.Second
+)
+
+var (
+	ErrNotFound = errors.New("api key not found")
+	ErrExpired  = errors.New("api key expired")
+)
+
+// Key is a long-lived credential for clients that cannot mint JWTs. Only the
+// hash of the key is kept, the key itself is shown once when it is created.
+type Key struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	Hash       string   `json:"hash"`
+	CreatedAt  int64    `json:"createdAt"`
+	LastUsedAt int64    `json:"lastUsedAt,omitempty"`
+	// ExpiresAt is when the key stops working, it never does when zero
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+}
+
+// Expired reports whether the key stopped working at now
+func (k Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != 0 && now.Unix() >= k.ExpiresAt
+}
+
+// Claims returns the claims requests authenticated with the key carry
+func (k Key) Claims() auth.Claims {
+	return auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "apikey:" + k.ID},
+		Scp:              k.Scopes,
+	}
+}
+
+// Store holds the API keys in a JSON file. The file is shared with the keys
+// command, so it is read again whenever it changes on disk.
+type Store struct {
+	path string
+
+	mu       sync.Mutex
+	modified time.Time
+	read     time.Time
+	keys     map[string]Key
+}
+
+// NewStore creates a store that persists to the JSON file at path
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, keys: map[string]Key{}}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create saves a new key and returns it along with the key itself
+func (s *Store) Create(name string, scopes []string, expiresAt int64) (Key, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Key{}, "", err
+	}
+	secret := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return Key{}, "", err
+	}
+	key := Key{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      hash(secret),
+		CreatedAt: time.Now().Unix(),
+		ExpiresAt: expiresAt,
+	}
+	s.keys[key.ID] = key
+
+	return key, secret, s.save()
+}
+
+// Revoke deletes a key, it stops working as soon as the server sees the change
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if _, ok := s.keys[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.keys, id)
+
+	return s.save()
+}
+
+// Keys returns every key, oldest first
+func (s *Store) Keys() ([]Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt != keys[j].CreatedAt {
+			return keys[i].CreatedAt < keys[j].CreatedAt
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys, nil
+}
+
+// Authenticate returns the key matching secret and records its use
+func (s *Store) Authenticate(secret string) (Key, error) {
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return Key{}, ErrNotFound
+	}
+	sum := hash(secret)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return Key{}, err
+	}
+	for id, key := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(sum)) != 1 {
+			continue
+		}
+		if key.Expired(now) {
+			return Key{}, ErrExpired
+		}
+		if now.Unix()-key.LastUsedAt >= int64(touchInterval.Seconds()) {
+			key.LastUsedAt = now.Unix()
+			s.keys[id] = key
+			if err := s.save(); err != nil {
+				return Key{}, err
+			}
+		}
+		return key, nil
+	}
+	return Key{}, ErrNotFound
+}
+
+// reload reads the file again when it changed, the caller holds the lock
+func (s *Store) reload() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modified) && s.read.Sub(s.modified) > racyWindow {
+		return nil
+	}
+
+	read := time.Now()
+	var keys []Key
+	if err := jsonfile.Load(s.path, &keys); err != nil {
+		return err
+	}
+	s.keys = make(map[string]Key, len(keys))
+	for _, key := range keys {
+		s.keys[key.ID] = key
+	}
+	s.modified, s.read = info.ModTime(), read
+	return nil
+}
+
+// save writes the store to its file, the caller holds the lock
+func (s *Store) save() error {
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	if err := jsonfile.Save(s.path, keys); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modified, s.read = info.ModTime(), time.Now()
+	}
+	return nil
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+)
+
+func newStore(t *testing.T) (*Store, string) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, path
+}
+
+func TestCreateStoresOnlyTheHash(t *testing.T) {
+	store, path := newStore(t)
+	key, secret, err := store.Create("tidbyt", []string{auth.ScopeCalendarRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, keyPrefix) {
+		t.Errorf("secret %q lacks the %s prefix", secret, keyPrefix)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), secret) || strings.Contains(string(data), strings.TrimPrefix(secret, keyPrefix)) {
+		t.Errorf("store file holds the secret:\n%s", data)
+	}
+	if !strings.Contains(string(data), key.Hash) || key.Hash != hash(secret) {
+		t.Errorf("store file does not hold the hash of the secret:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("store file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	store, _ := newStore(t)
+	key, secret, err := store.Create("tidbyt", []string{auth.ScopeCalendarRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, expired, err := store.Create("old", []string{auth.ScopeCalendarRead}, time.Now().Add(-time.Second).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, later, err := store.Create("later", []string{auth.ScopeCalendarRead}, time.Now().Add(time.Hour).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		err    error
+	}{
+		{name: "valid", secret: secret},
+		{name: "expires later", secret: later},
+		{name: "expired", secret: expired, err: ErrExpired},
+		{name: "unknown", secret: keyPrefix + "unknown", err: ErrNotFound},
+		{name: "no prefix", secret: strings.TrimPrefix(secret, keyPrefix), err: ErrNotFound},
+		{name: "hash", secret: key.Hash, err: ErrNotFound},
+		{name: "blank", secret: "", err: ErrNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := store.Authenticate(test.secret)
+			if !errors.Is(err, test.err) {
+				t.Errorf("Authenticate error = %v, want %v", err, test.err)
+			}
+		})
+	}
+
+	got, err := store.Authenticate(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != key.ID || got.LastUsedAt == 0 {
+		t.Errorf("Authenticate = %+v, want key %s with its use recorded", got, key.ID)
+	}
+	if claims := got.Claims(); claims.Subject != "apikey:"+key.ID || !claims.HasScopes(auth.ScopeCalendarRead) || claims.HasScopes(auth.ScopeAdmin) {
+		t.Errorf("Claims() = %+v, want the subject and scopes of the key", claims)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	store, _ := newStore(t)
+	key, secret, err := store.Create("tidbyt", []string{auth.ScopeCalendarRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Revoke(key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Authenticate(secret); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Authenticate of a revoked key error = %v, want ErrNotFound", err)
+	}
+	if err := store.Revoke(key.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revoke of a revoked key error = %v, want ErrNotFound", err)
+	}
+}
+
+// The server and the keys command share the file, each picks up the changes
+// of the other
+func TestStoresShareTheFile(t *testing.T) {
+	server, path := newStore(t)
+	command, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, secret, err := command.Create("tidbyt", []string{auth.ScopeCalendarRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Authenticate(secret); err != nil {
+		t.Fatalf("server does not see the created key: %v", err)
+	}
+
+	// The server recorded the use, the command keeps it when it writes
+	if _, _, err := command.Create("other", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := server.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := false
+	for _, k := range keys {
+		used = used || (k.ID == key.ID && k.LastUsedAt != 0)
+	}
+	if len(keys) != 2 || !used {
+		t.Errorf("Keys() = %+v, want both keys and the recorded use", keys)
+	}
+
+	if err := command.Revoke(key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Authenticate(secret); !errors.Is(err, ErrNotFound) {
+		t.Errorf("server still accepts the revoked key: %v", err)
+	}
+}
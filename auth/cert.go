@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/x509"
+	"strings"
+)
+
+// CertIdentity grants scopes to client certificates, Subject is matched
+// against the common name or the whole distinguished name, e.g.
+// CN=tidbyt,O=Home
+type CertIdentity struct {
+	Subject string   `required:"true"`
+	Scopes  []string `oneof:"calendar:read admin"`
+}
+
+// CertClaims returns the claims of a verified client certificate. Its subject
+// is cert: followed by the common name, certificates no identity matches are
+// granted no scopes.
+func CertClaims(cert *x509.Certificate, identities []CertIdentity) Claims {
+	name := cert.Subject.CommonName
+	if name == "" {
+		name = cert.Subject.String()
+	}
+
+	claims := Claims{}
+	claims.Subject = "cert:" + name
+	for _, identity := range identities {
+		if identity.Subject == cert.Subject.CommonName || strings.EqualFold(identity.Subject, cert.Subject.String()) {
+			claims.Scp = append(claims.Scp, identity.Scopes...)
+		}
+	}
+	return claims
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoKey          = errors.New("no key matches the token")
+	ErrAlgorithm      = errors.New("token algorithm does not match the key")
+	ErrKeyFormat      = errors.New("key file is neither PEM nor a JWKS")
+	ErrUnsupportedKey = errors.New("unsupported key type")
+)
+
+// KeySet holds the public keys tokens are verified with. It is read from a
+// PEM file holding a single key or a JWKS file holding keys by kid, and read
+// again whenever the file changes, so keys can be rotated by rewriting it.
+type KeySet struct {
+	path string
+
+	mu       sync.RWMutex
+	modified time.Time
+	keys     map[string]crypto.PublicKey
+}
+
+// NewKeySet loads the keys of the PEM or JWKS file at path
+func NewKeySet(path string) (*KeySet, error) {
+	ks := &KeySet{path: path}
+	if err := ks.reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Keyfunc picks the key of a token by its kid, reloading the file first when
+// it changed. A PEM key is used for every token.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if err := ks.reload(); err != nil {
+		return nil, err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		key, ok = ks.keys[""]
+	}
+	if !ok {
+		return nil, ErrNoKey
+	}
+
+	if !matches(token.Method, key) {
+		return nil, ErrAlgorithm
+	}
+	return key, nil
+}
+
+// reload reads the file again when its modification time changed
+func (ks *KeySet) reload() error {
+	info, err := os.Stat(ks.path)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.RLock()
+	current := info.ModTime().Equal(ks.modified)
+	ks.mu.RUnlock()
+	if current {
+		return nil
+	}
+
+	data, err := os.ReadFile(ks.path)
+	if err != nil {
+		return err
+	}
+	keys, err := parseKeys(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", ks.path, err)
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.modified = info.ModTime()
+	ks.mu.Unlock()
+	return nil
+}
+
+// matches reports whether a signing method verifies with the key, so a token
+// cannot pick an algorithm the key was not meant for
+func matches(method jwt.SigningMethod, key crypto.PublicKey) bool {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return method == jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		return method == jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		return method == jwt.SigningMethodEdDSA
+	}
+	return false
+}
+
+func parseKeys(data []byte) (map[string]crypto.PublicKey, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		return parseJWKS(data)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrKeyFormat
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !supported(key) {
+		return nil, ErrUnsupportedKey
+	}
+	return map[string]crypto.PublicKey{"": key}, nil
+}
+
+func supported(key crypto.PublicKey) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return true
+	case *ecdsa.PublicKey:
+		return k.Curve == elliptic.P256()
+	}
+	return false
+}
+
+// jwk is a JSON Web Key as described in RFC 7517, RFC 7518 and RFC 8037
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the signature keys of a JWKS by kid, keys of other uses
+// and types are skipped
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if errors.Is(err, ErrUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKey
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch {
+	case k.Kty == "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("bad Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, ErrUnsupportedKey
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is an Ed25519 key published in a JWKS under kid
+type signingKey struct {
+	kid     string
+	private ed25519.PrivateKey
+}
+
+func newSigningKey(t *testing.T, kid string) signingKey {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingKey{kid: kid, private: private}
+}
+
+func (k signingKey) jwk() string {
+	x := base64.RawURLEncoding.EncodeToString(k.private.Public().(ed25519.PublicKey))
+	return fmt.Sprintf(`{"kty":"OKP","crv":"Ed25519","use":"sig","kid":%q,"x":%q}`, k.kid, x)
+}
+
+func (k signingKey) token(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "device",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}})
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// writeJWKS writes a JWKS holding jwks, moving the modification time on so
+// the key set notices the change
+func writeJWKS(t *testing.T, path string, modified time.Time, jwks ...string) {
+	data := `{"keys":[` + strings.Join(jwks, ",") + `]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	old, next := newSigningKey(t, "old"), newSigningKey(t, "new")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	start := time.Now().Add(-time.Hour)
+
+	// Keys of other uses and types are skipped
+	encryption := `{"kty":"OKP","crv":"Ed25519","use":"enc","kid":"enc","x":"AAAA"}`
+	symmetric := `{"kty":"oct","kid":"hmac","k":"c2VjcmV0"}`
+	writeJWKS(t, path, start, old.jwk(), encryption, symmetric)
+	keys, err := NewKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &Verifier{Keys: keys}
+
+	if _, err := verifier.Verify(old.token(t)); err != nil {
+		t.Errorf("token of the published key: %v", err)
+	}
+	if _, err := verifier.Verify(next.token(t)); !errors.Is(err, ErrNoKey) {
+		t.Errorf("token of an unpublished key error = %v, want ErrNoKey", err)
+	}
+
+	// Publishing the new key next to the old one accepts both
+	writeJWKS(t, path, start.Add(time.Minute), old.jwk(), next.jwk())
+	for _, key := range []signingKey{old, next} {
+		if _, err := verifier.Verify(key.token(t)); err != nil {
+			t.Errorf("token of %s during rotation: %v", key.kid, err)
+		}
+	}
+
+	// Dropping the old key rejects its tokens straight away
+	writeJWKS(t, path, start.Add(2*time.Minute), next.jwk())
+	if _, err := verifier.Verify(next.token(t)); err != nil {
+		t.Errorf("token of the new key: %v", err)
+	}
+	if _, err := verifier.Verify(old.token(t)); !errors.Is(err, ErrNoKey) {
+		t.Errorf("token of the dropped key error = %v, want ErrNoKey", err)
+	}
+
+	// A broken rewrite fails verification rather than keeping stale keys
+	writeJWKS(t, path, start.Add(3*time.Minute), symmetric)
+	if _, err := verifier.Verify(next.token(t)); err == nil {
+		t.Error("token verified against a key set without signature keys")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	pemPath, _ := writeKey(t, newSigningKey(t, "").private.Public())
+	pemData, err := os.ReadFile(pemPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		kids []string
+		err  error
+	}{
+		{name: "PEM", data: string(pemData), kids: []string{""}},
+		{name: "JWKS", data: `{"keys":[` + newSigningKey(t, "a").jwk() + `,` + newSigningKey(t, "b").jwk() + `]}`, kids: []string{"a", "b"}},
+		{name: "empty JWKS", data: `{"keys":[]}`, err: ErrNoKey},
+		{name: "neither", data: "secret", err: ErrKeyFormat},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, err := parseKeys([]byte(test.data))
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("parseKeys error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, kid := range test.kids {
+				if _, ok := keys[kid]; !ok {
+					t.Errorf("parseKeys = %v, want kid %q", keys, kid)
+				}
+			}
+		})
+	}
+}
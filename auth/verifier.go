@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/sliceutil"
+)
+
+const (
+	ScopeCalendarRead = "calendar:read"
+	ScopeAdmin        = "admin"
+
+	// leeway tolerates clock skew between the issuer and the server
+	leeway = 30 * time.Second
+)
+
+// Scopes are the scopes routes can require
+var Scopes = []string{ScopeCalendarRead, ScopeAdmin}
+
+// Claims are the claims of an access token. Scopes are read from the OAuth
+// scope claim, a space separated string, or from scp, a list.
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope,omitempty"`
+	Scp   []string `json:"scp,omitempty"`
+}
+
+// Scopes returns every scope the token grants
+func (c Claims) Scopes() []string {
+	return append(strings.Fields(c.Scope), c.Scp...)
+}
+
+// HasScopes reports whether the token grants every one of scopes
+func (c Claims) HasScopes(scopes ...string) bool {
+	return sliceutil.ContainsStrings(scopes, c.Scopes())
+}
+
+// Verifier checks bearer tokens against a key set
+type Verifier struct {
+	Keys *KeySet
+	// Audience must be in the aud claim when set
+	Audience string
+	// Issuer must be the iss claim when set
+	Issuer string
+}
+
+// Verify checks the signature, expiry, not before and audience of a token and
+// returns its claims. Tokens without an expiry are rejected.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+	if v.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.Audience))
+	}
+	if v.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.Issuer))
+	}
+
+	var claims Claims
+	if _, err := jwt.ParseWithClaims(token, &claims, v.Keys.Keyfunc, opts...); err != nil {
+		return Claims{}, err
+	}
+	return claims, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the claims of a verified token
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims of the verified token of a request, if any
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(Claims)
+	return claims, ok
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey writes a public key as PEM and returns the file and its contents
+func writeKey(t *testing.T, key interface{}) (string, []byte) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func mustRSA(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.Claims) string {
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey := mustRSA(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, pemData := writeKey(t, &rsaKey.PublicKey)
+	keys, err := NewKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &Verifier{Keys: keys, Audience: "ics", Issuer: "https://issuer.example.com"}
+
+	now := time.Now()
+	claims := func(modify func(*Claims)) Claims {
+		c := Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "device",
+				Issuer:    "https://issuer.example.com",
+				Audience:  jwt.ClaimStrings{"ics"},
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			},
+			Scope: ScopeCalendarRead,
+		}
+		modify(&c)
+		return c
+	}
+	valid := func(*Claims) {}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{name: "valid", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(valid)), ok: true},
+		{name: "alg none", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims(valid))},
+		// The public key is no HMAC secret, whatever the token claims
+		{name: "HS256 with the public key", token: sign(t, jwt.SigningMethodHS256, pemData, claims(valid))},
+		{name: "RS512", token: sign(t, jwt.SigningMethodRS512, rsaKey, claims(valid))},
+		// ES256 is accepted, but not with an RSA key
+		{name: "ES256 for an RSA key", token: sign(t, jwt.SigningMethodES256, ecKey, claims(valid))},
+		{name: "other signer", token: sign(t, jwt.SigningMethodRS256, mustRSA(t), claims(valid))},
+		{name: "expired", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute))
+		}))},
+		{name: "expired within leeway", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-10 * time.Second))
+		})), ok: true},
+		{name: "no expiry", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.ExpiresAt = nil
+		}))},
+		{name: "not yet valid", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.NotBefore = jwt.NewNumericDate(now.Add(time.Minute))
+		}))},
+		{name: "not before within leeway", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.NotBefore = jwt.NewNumericDate(now.Add(10 * time.Second))
+		})), ok: true},
+		{name: "other audience", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.Audience = jwt.ClaimStrings{"other"}
+		}))},
+		{name: "one of several audiences", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.Audience = jwt.ClaimStrings{"other", "ics"}
+		})), ok: true},
+		{name: "no audience", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.Audience = nil
+		}))},
+		{name: "other issuer", token: sign(t, jwt.SigningMethodRS256, rsaKey, claims(func(c *Claims) {
+			c.Issuer = "https://other.example.com"
+		}))},
+		{name: "garbage", token: "not.a.token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := verifier.Verify(test.token)
+			if test.ok != (err == nil) {
+				t.Fatalf("Verify error = %v, want ok %v", err, test.ok)
+			}
+			if test.ok && (got.Subject != "device" || !got.HasScopes(ScopeCalendarRead)) {
+				t.Errorf("Verify = %+v, want the claims of the token", got)
+			}
+		})
+	}
+}
+
+func TestClaimsScopes(t *testing.T) {
+	claims := Claims{Scope: "calendar:read  profile", Scp: []string{ScopeAdmin}}
+
+	if !claims.HasScopes(ScopeCalendarRead, ScopeAdmin) {
+		t.Errorf("HasScopes(%v) = false for %+v", Scopes, claims)
+	}
+	if claims.HasScopes("calendar:write") {
+		t.Errorf("HasScopes(calendar:write) = true for %+v", claims)
+	}
+	if !(Claims{}).HasScopes() {
+		t.Error("HasScopes() without scopes = false")
+	}
+}
@@ -1,36 +1,124 @@
 package calendar
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/apognu/gocal"
+	"github.com/apognu/gocal/parser"
 	"github.com/go-resty/resty/v2"
 	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
 	"go.uber.org/zap"
 )
 
+const (
+	// DefaultLookaheadDays is how far ahead of now events are considered when
+	// the request does not set a window
+	DefaultLookaheadDays = 7
+	// MaxLookaheadDays is the longest window a request may ask for
+	MaxLookaheadDays = 31
+	// DefaultFetchTimeout bounds a feed download when the calendar sets no timeout
+	DefaultFetchTimeout = 30 * time.Second
+	// DefaultMaxFeedBytes is the largest feed downloaded when the calendar sets no limit
+	DefaultMaxFeedBytes = 10 << 20
+)
+
+var (
+	ErrFeedUnreachable = errors.New("calendar feed unreachable")
+	ErrFeedNotICS      = errors.New("calendar feed is not ICS")
+	ErrBadTZ           = errors.New("unknown time zone")
+	ErrBadFilter       = errors.New("invalid filter")
+)
+
 type Calendar struct {
 	Logger *zap.Logger
-	TZMap  map[string]string
+	// Settings are used until Reconfigure replaces them
+	Settings
+
+	reconfigured atomic.Pointer[Settings]
 }
 
-func (c Calendar) DownloadCalendar(url string) (string, error) {
-	client := resty.New()
+// Settings are the parts of a Calendar that can change while it serves
+// requests
+type Settings struct {
+	TZMap      map[string]string
+	ColorRules []t.ColorRule
+	// MaxLookaheadDays caps the window of requests, it cannot exceed
+	// MaxLookaheadDays and falls back to it when zero
+	MaxLookaheadDays int
+	// FetchTimeout bounds a feed download, DefaultFetchTimeout when zero
+	FetchTimeout time.Duration
+	// MaxFeedBytes is the largest feed that is downloaded,
+	// DefaultMaxFeedBytes when zero
+	MaxFeedBytes int64
+}
 
-	resp, err := client.R().Get(url)
+// Reconfigure replaces the settings of the calendar. Requests already reading
+// the old settings finish with them, the settings must not be modified after.
+func (c *Calendar) Reconfigure(s Settings) {
+	c.reconfigured.Store(&s)
+}
+
+// settings returns the current settings
+func (c *Calendar) settings() *Settings {
+	if s := c.reconfigured.Load(); s != nil {
+		return s
+	}
+	return &c.Settings
+}
+
+func (c *Calendar) DownloadCalendar(url string) (string, error) {
+	settings := c.settings()
+	timeout, maxBytes := settings.FetchTimeout, settings.MaxFeedBytes
+	if timeout <= 0 {
+		timeout = DefaultFetchTimeout
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxFeedBytes
+	}
+	client := resty.New().SetTimeout(timeout)
+
+	// webcal:// is how calendar apps advertise subscriptions, it is plain HTTPS
+	if strings.HasPrefix(url, "webcal://") {
+		url = "https://" + strings.TrimPrefix(url, "webcal://")
+	}
+
+	resp, err := client.R().SetDoNotParseResponse(true).Get(url)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrFeedUnreachable, err)
+	}
+	body := resp.RawBody()
+	defer body.Close()
+	if resp.IsError() {
+		return "", fmt.Errorf("%w: status %d", ErrFeedUnreachable, resp.StatusCode())
+	}
+
+	// One byte over the limit tells a feed at the limit from a larger one
+	data, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %v", ErrFeedUnreachable, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return "", fmt.Errorf("%w: feed is larger than %d bytes", ErrFeedUnreachable, maxBytes)
 	}
 
-	return resp.String(), err
+	return string(data), nil
 }
 
-func (c Calendar) ParseCalendar(data string, tz string) ([]t.Event, error) {
+func (c *Calendar) ParseCalendar(data string, tz string, windowDays int) ([]t.Event, error) {
+	if !strings.Contains(data, "BEGIN:VCALENDAR") {
+		return nil, ErrFeedNotICS
+	}
+
+	tzMap := c.settings().TZMap
 	gocal.SetTZMapper(func(s string) (*time.Location, error) {
 		override := ""
-		if val, ok := c.TZMap[s]; ok {
+		if val, ok := tzMap[s]; ok {
 			override = val
 		}
 		if override != "" {
@@ -53,51 +141,121 @@ func (c Calendar) ParseCalendar(data string, tz string) ([]t.Event, error) {
 	usersLoc, err := time.LoadLocation(tz)
 	if err != nil {
 		c.Logger.Error("Error", zap.Any("err", err))
-		return nil, err
+		return nil, fmt.Errorf("%w: %q", ErrBadTZ, tz)
 	}
 
 	parser := gocal.NewParser(strings.NewReader(data))
-	start, end := time.Now().In(usersLoc), time.Now().AddDate(0, 0, 7).In(usersLoc)
+	start, end := time.Now().In(usersLoc), c.WindowEnd(time.Now(), windowDays).In(usersLoc)
 	parser.Start, parser.End = &start, &end
 
 	parser.Parse()
 
+	props := calendarProperties(data)
+
 	var events []t.Event
 	for _, e := range parser.Events {
+		// Every event needs its own copy, the loop variable is reused
+		location := e.Location
 		events = append(events, t.Event{
-			Name:      e.Summary,
-			StartTime: e.Start.Unix(),
-			EndTime:   e.End.Unix(),
-			Location:  &e.Location,
+			UID:          e.Uid,
+			RecurrenceID: recurrenceID(e),
+			Cancelled:    strings.EqualFold(e.Status, "CANCELLED"),
+			Name:         e.Summary,
+			StartTime:    e.Start.Unix(),
+			EndTime:      e.End.Unix(),
+			Location:     &location,
+			Calendar:     props.name(),
+			Categories:   e.Categories,
+			Colors:       t.Colors{Source: props.color(e)},
 		})
 	}
 
-	c.Logger.Info("ParseCalendar", zap.Any("events", events))
+	c.Logger.Debug("ParseCalendar", zap.Any("events", events))
 
 	return events, nil
 }
 
-func (c Calendar) NextEvent(events []t.Event) *t.Event {
+// recurrenceID returns the original start of an occurrence of a recurring
+// event, occurrences share a UID and are told apart by it
+func recurrenceID(e gocal.Event) int64 {
+	if e.RecurrenceID != "" {
+		if start, err := parser.ParseTime(e.RecurrenceID, map[string]string{}, parser.TimeStart, false); err == nil {
+			return start.Unix()
+		}
+	}
+	if e.IsRecurring {
+		return e.Start.Unix()
+	}
+	return 0
+}
+
+// WindowEnd returns the end of a lookahead window of days that starts at now,
+// falling back to DefaultLookaheadDays when days is not set
+func (c *Calendar) WindowEnd(now time.Time, days int) time.Time {
+	maxDays := MaxLookaheadDays
+	if days := c.settings().MaxLookaheadDays; days > 0 {
+		maxDays = min(days, MaxLookaheadDays)
+	}
+	if days <= 0 {
+		days = DefaultLookaheadDays
+	}
+	return now.AddDate(0, 0, min(days, maxDays))
+}
+
+// Filter drops the events a request does not want to see and returns the
+// remaining events along with how many were dropped
+func (c *Calendar) Filter(events []t.Event, req t.IcsRequest) ([]t.Event, int, error) {
+	filter, err := ParseFilter(req.Filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrBadFilter, err)
+	}
+
+	now := time.Now().Unix()
+
+	var kept []t.Event
+	for _, e := range events {
+		if !req.ShowInProgress && e.StartTime <= now {
+			continue
+		}
+		if !filter.Match(e) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+
+	return kept, len(events) - len(kept), nil
+}
+
+func (c *Calendar) NextEvent(events []t.Event) *t.Event {
 	var next t.Event
 
+	if len(events) == 0 {
+		return nil
+	}
+
 	now := time.Now().Unix()
 
 	sort.Slice(events, func(i, j int) bool {
 		return events[i].StartTime < events[j].StartTime
 	})
 	next = events[0]
+	c.Annotate(&next, now)
 
-	fiveMinutesFromStart := next.StartTime - 5*60
-	tenMinutesFromStart := next.StartTime - 10*60
-	oneMinuteFromStart := next.StartTime - 60
+	c.Logger.Debug("NextEvent", zap.Any("nextEvent", next), zap.Int64("now", now))
 
-	next.FiveMinuteWarning = now >= fiveMinutesFromStart && now < tenMinutesFromStart
-	next.TenMinuteWarning = now >= tenMinutesFromStart && now < fiveMinutesFromStart
-	next.OneMinuteWarning = now >= oneMinuteFromStart && now < tenMinutesFromStart
-	next.InProgress = now >= next.StartTime
+	return &next
+}
 
-	c.Logger.Info("NextEvent", zap.Any("nextEvent", next))
-	c.Logger.Info("Now", zap.Any("now", now))
+// Annotate sets the warning flags and countdown fields of an event
+func (c *Calendar) Annotate(e *t.Event, now int64) {
+	fiveMinutesFromStart := e.StartTime - 5*60
+	tenMinutesFromStart := e.StartTime - 10*60
+	oneMinuteFromStart := e.StartTime - 60
 
-	return &next
+	// each warning holds until the next, shorter one takes over
+	e.TenMinuteWarning = now >= tenMinutesFromStart && now < fiveMinutesFromStart
+	e.FiveMinuteWarning = now >= fiveMinutesFromStart && now < oneMinuteFromStart
+	e.OneMinuteWarning = now >= oneMinuteFromStart && now < e.StartTime
+	e.InProgress = now >= e.StartTime
+	c.Countdown(e, now)
 }
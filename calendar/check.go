@@ -0,0 +1,28 @@
+package calendar
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+// CheckFeedURL reports whether value is a feed URL the calendar can download
+func CheckFeedURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return errors.New("must be an absolute URL")
+	}
+	switch u.Scheme {
+	case "http", "https", "webcal":
+		return nil
+	}
+	return errors.New("must use the http, https or webcal scheme")
+}
+
+// CheckTZ reports whether value is an IANA time zone
+func CheckTZ(value string) error {
+	if _, err := time.LoadLocation(value); err != nil {
+		return errors.New("must be an IANA time zone such as America/Chicago")
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package calendar
+
+import (
+	"math"
+
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// WarningOffsets are the seconds before an event starts at which a warning
+// flag switches on and the display changes
+var WarningOffsets = []int64{10 * 60, 5 * 60, 60}
+
+// Countdown fills in the countdown and progress fields the display widgets draw
+// from, along with the moment the display state next changes
+func (c *Calendar) Countdown(e *t.Event, now int64) {
+	e.SecondsUntilStart = max(e.StartTime-now, 0)
+	e.SecondsUntilEnd = max(e.EndTime-now, 0)
+
+	e.PercentElapsed = 0
+	if duration := e.EndTime - e.StartTime; duration > 0 {
+		elapsed := float64(min(max(now-e.StartTime, 0), duration))
+		e.PercentElapsed = math.Round(elapsed/float64(duration)*1000) / 10
+	}
+
+	e.RefreshAt = nextChange(*e, now)
+	e.RefreshInSeconds = e.RefreshAt - now
+}
+
+// nextChange returns the first warning threshold, start or end after now.
+// Once the event has ended the display should refresh straight away.
+func nextChange(e t.Event, now int64) int64 {
+	var changes []int64
+	for _, offset := range WarningOffsets {
+		changes = append(changes, e.StartTime-offset)
+	}
+	changes = append(changes, e.StartTime, e.EndTime)
+
+	next := int64(math.MaxInt64)
+	for _, change := range changes {
+		if change > now && change < next {
+			next = change
+		}
+	}
+
+	if next == math.MaxInt64 {
+		return now
+	}
+	return next
+}
@@ -0,0 +1,64 @@
+package calendar
+
+import (
+	"testing"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+func TestAnnotate(t *testing.T) {
+	const start, end = 10000, 13600
+
+	tests := []struct {
+		name                       string
+		now                        int64
+		ten, five, one, inProgress bool
+		refreshAt                  int64
+	}{
+		{name: "before ten minutes", now: start - 11*60, refreshAt: start - 10*60},
+		{name: "at ten minutes", now: start - 10*60, ten: true, refreshAt: start - 5*60},
+		{name: "just before five minutes", now: start - 5*60 - 1, ten: true, refreshAt: start - 5*60},
+		{name: "at five minutes", now: start - 5*60, five: true, refreshAt: start - 60},
+		{name: "just before one minute", now: start - 61, five: true, refreshAt: start - 60},
+		{name: "at one minute", now: start - 60, one: true, refreshAt: start},
+		{name: "just before start", now: start - 1, one: true, refreshAt: start},
+		{name: "at start", now: start, inProgress: true, refreshAt: end},
+		{name: "during", now: start + 1800, inProgress: true, refreshAt: end},
+		{name: "after end", now: end + 60, inProgress: true, refreshAt: end + 60},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := types.Event{StartTime: start, EndTime: end}
+			new(Calendar).Annotate(&e, test.now)
+
+			if e.TenMinuteWarning != test.ten || e.FiveMinuteWarning != test.five ||
+				e.OneMinuteWarning != test.one || e.InProgress != test.inProgress {
+				t.Errorf("warnings ten=%v five=%v one=%v inProgress=%v, want %v %v %v %v",
+					e.TenMinuteWarning, e.FiveMinuteWarning, e.OneMinuteWarning, e.InProgress,
+					test.ten, test.five, test.one, test.inProgress)
+			}
+			if e.RefreshAt != test.refreshAt {
+				t.Errorf("RefreshAt = %d, want %d", e.RefreshAt, test.refreshAt)
+			}
+		})
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	e := types.Event{StartTime: 1000, EndTime: 2000}
+
+	new(Calendar).Countdown(&e, 400)
+	if e.SecondsUntilStart != 600 || e.SecondsUntilEnd != 1600 || e.PercentElapsed != 0 {
+		t.Errorf("before start: %+v", e)
+	}
+
+	new(Calendar).Countdown(&e, 1333)
+	if e.SecondsUntilStart != 0 || e.SecondsUntilEnd != 667 || e.PercentElapsed != 33.3 {
+		t.Errorf("during: %+v", e)
+	}
+
+	new(Calendar).Countdown(&e, 2500)
+	if e.SecondsUntilEnd != 0 || e.PercentElapsed != 100 || e.RefreshInSeconds != 0 {
+		t.Errorf("after end: %+v", e)
+	}
+}
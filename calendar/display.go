@@ -0,0 +1,23 @@
+package calendar
+
+import (
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/glyph"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/layout"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// Display prepares an event for the Tidbyt. The name and location are rewritten
+// into glyphs the pixel fonts can draw, keeping the originals in the Raw fields,
+// the name is laid out for every font and the colors are resolved.
+func (c *Calendar) Display(e *t.Event, req t.IcsRequest) {
+	opts := glyph.Options{Placeholder: req.GlyphPlaceholder}
+
+	e.RawName, e.Name = e.Name, glyph.Sanitize(e.Name, opts)
+	if e.Location != nil {
+		raw, location := *e.Location, glyph.Sanitize(*e.Location, opts)
+		e.RawLocation, e.Location = &raw, &location
+	}
+
+	e.Lines = layout.Lines(e.Name, req.Width, req.MaxLines)
+	c.Colorize(e, req.Theme)
+}
@@ -0,0 +1,82 @@
+package calendar
+
+import (
+	"sort"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/redact"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+// Events downloads and parses the feed of a request and applies its filters.
+// It returns the kept events along with how many were filtered out.
+func (c *Calendar) Events(req t.IcsRequest) ([]t.Event, int, error) {
+	calString, err := c.DownloadCalendar(req.ICSUrl)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.Logger.Info("Events", zap.String("url", redact.URL(req.ICSUrl)), zap.String("feed", redact.Summary(calString)))
+
+	events, err := c.ParseCalendar(calString, req.TZ, req.WindowDays)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return c.Filter(events, req)
+}
+
+// Next returns the display state for the next event of the request's feed
+func (c *Calendar) Next(req t.IcsRequest) (t.NextEventResponse, error) {
+	events, filteredOut, err := c.Events(req)
+	if err != nil {
+		return t.NextEventResponse{}, err
+	}
+
+	nextEvent := c.NextEvent(events)
+	if nextEvent != nil {
+		c.Display(nextEvent, req)
+	}
+
+	return c.State(nextEvent, filteredOut, req.WindowDays, time.Now()), nil
+}
+
+// List returns every event of the request's feed in start order, prepared for display
+func (c *Calendar) List(req t.IcsRequest) ([]t.Event, int, error) {
+	events, filteredOut, err := c.Events(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StartTime < events[j].StartTime
+	})
+
+	now := time.Now().Unix()
+	for i := range events {
+		c.Annotate(&events[i], now)
+		c.Display(&events[i], req)
+	}
+
+	return events, filteredOut, nil
+}
+
+// FreeBusy merges events into the sorted, non-overlapping periods they keep busy
+func (c *Calendar) FreeBusy(events []t.Event) []t.BusyPeriod {
+	sorted := append([]t.Event{}, events...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartTime < sorted[j].StartTime
+	})
+
+	var busy []t.BusyPeriod
+	for _, e := range sorted {
+		if n := len(busy); n > 0 && e.StartTime <= busy[n-1].EndTime {
+			busy[n-1].EndTime = max(busy[n-1].EndTime, e.EndTime)
+			continue
+		}
+		busy = append(busy, t.BusyPeriod{StartTime: e.StartTime, EndTime: e.EndTime})
+	}
+
+	return busy
+}
@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/sliceutil"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// filterFields are the event fields a filter term can match on
+var filterFields = []string{"name", "location", "category", "calendar"}
+
+type filterTerm struct {
+	field   string
+	value   string
+	exclude bool
+}
+
+// EventFilter selects events by name, location, category or calendar. It is
+// written as comma separated field:value terms, e.g. "category:work,-name:lunch".
+// Values match case insensitive substrings. An event is kept when it matches
+// every plain term and none of the terms prefixed with -.
+type EventFilter []filterTerm
+
+// ParseFilter parses the filter syntax described on EventFilter
+func ParseFilter(s string) (EventFilter, error) {
+	var filter EventFilter
+
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		exclude := strings.HasPrefix(term, "-")
+		field, value, ok := strings.Cut(strings.TrimPrefix(term, "-"), ":")
+		field, value = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("filter term %q must look like field:value", term)
+		}
+		if !sliceutil.ContainsString(filterFields, field) {
+			return nil, fmt.Errorf("filter term %q uses unknown field %q, use one of %s", term, field, strings.Join(filterFields, ", "))
+		}
+
+		filter = append(filter, filterTerm{field: field, value: strings.ToLower(value), exclude: exclude})
+	}
+
+	return filter, nil
+}
+
+// Match reports whether the filter keeps the event
+func (f EventFilter) Match(e t.Event) bool {
+	for _, term := range f {
+		if term.matches(e) == term.exclude {
+			return false
+		}
+	}
+	return true
+}
+
+func (term filterTerm) matches(e t.Event) bool {
+	var values []string
+	switch term.field {
+	case "name":
+		values = []string{e.Name}
+	case "location":
+		if e.Location != nil {
+			values = []string{*e.Location}
+		}
+	case "category":
+		values = e.Categories
+	case "calendar":
+		values = []string{e.Calendar}
+	}
+
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), term.value) {
+			return true
+		}
+	}
+	return false
+}
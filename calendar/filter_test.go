@@ -0,0 +1,69 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+const icsTime = "20060102T150405Z"
+
+// roomsFeed returns an ICS feed with an event in each room, an hour apart
+func roomsFeed(rooms ...string) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n")
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Minute)
+	for i, room := range rooms {
+		from := start.Add(time.Duration(i) * time.Hour)
+		fmt.Fprintf(&b, "BEGIN:VEVENT\r\nUID:event-%d\r\nDTSTAMP:%s\r\nSUMMARY:Meeting %d\r\nLOCATION:%s\r\nDTSTART:%s\r\nDTEND:%s\r\nEND:VEVENT\r\n",
+			i, start.Format(icsTime), i, room, from.Format(icsTime), from.Add(30*time.Minute).Format(icsTime))
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+	return b.String()
+}
+
+func TestFilterByLocation(t *testing.T) {
+	cal := &Calendar{Logger: zap.NewNop()}
+	events, err := cal.ParseCalendar(roomsFeed("Room A", "Room B", "Room C"), "UTC", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter      string
+		kept        []string
+		filteredOut int
+	}{
+		{filter: "location:Room A", kept: []string{"Meeting 0"}, filteredOut: 2},
+		{filter: "location:room b", kept: []string{"Meeting 1"}, filteredOut: 2},
+		{filter: "-location:Room A", kept: []string{"Meeting 1", "Meeting 2"}, filteredOut: 1},
+		{filter: "location:Room", kept: []string{"Meeting 0", "Meeting 1", "Meeting 2"}, filteredOut: 0},
+		{filter: "location:Room A,name:Meeting 2", kept: nil, filteredOut: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			kept, filteredOut, err := cal.Filter(events, types.IcsRequest{Filter: test.filter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filteredOut != test.filteredOut {
+				t.Errorf("filteredOut = %d, want %d", filteredOut, test.filteredOut)
+			}
+			if got := names(kept); strings.Join(got, "|") != strings.Join(test.kept, "|") {
+				t.Errorf("kept %q, want %q", got, test.kept)
+			}
+		})
+	}
+}
+
+func names(events []types.Event) []string {
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	return names
+}
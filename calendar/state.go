@@ -0,0 +1,65 @@
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// idleRefresh is how many seconds an idle display waits before checking the feed again
+const idleRefresh = 5 * 60
+
+// State describes what the display should show. next is nil when no event is
+// left in the lookahead window, the display is then idle until the window ends.
+func (c *Calendar) State(next *t.Event, filteredOut, windowDays int, now time.Time) t.NextEventResponse {
+	resp := t.NextEventResponse{
+		State:       t.StateIdle,
+		Event:       next,
+		WindowEnd:   c.WindowEnd(now, windowDays).Unix(),
+		FilteredOut: filteredOut,
+		RefreshAt:   now.Unix() + idleRefresh,
+	}
+
+	if next != nil {
+		resp.State = t.StateUpcoming
+		if next.InProgress {
+			resp.State = t.StateInProgress
+		}
+		resp.RefreshAt = next.RefreshAt
+	}
+	resp.RefreshInSeconds = resp.RefreshAt - now.Unix()
+
+	return resp
+}
+
+// Changed reports whether the display has to be redrawn, ignoring the countdown
+// fields that change every second
+func Changed(prev, cur t.NextEventResponse) bool {
+	return DisplayKey(prev) != DisplayKey(cur)
+}
+
+// DisplayKey identifies what the display shows for a response: every field
+// except the countdowns and refresh hints that change every second. It only
+// changes when Changed reports a change.
+func DisplayKey(resp t.NextEventResponse) string {
+	shown := struct {
+		State       t.EventState
+		Event       *t.Event
+		FilteredOut int
+	}{State: resp.State, FilteredOut: resp.FilteredOut}
+
+	if resp.Event != nil {
+		e := *resp.Event
+		e.SecondsUntilStart, e.SecondsUntilEnd, e.PercentElapsed = 0, 0, 0
+		e.RefreshAt, e.RefreshInSeconds = 0, 0
+		shown.Event = &e
+	}
+
+	// Map keys are sorted, so equal states always encode the same way
+	data, _ := json.Marshal(shown)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,69 @@
+package calendar
+
+import (
+	"testing"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+func TestChangedIgnoresCountdown(t *testing.T) {
+	event := types.Event{Name: "Standup", StartTime: 1000, EndTime: 2000}
+	prev := types.NextEventResponse{State: types.StateUpcoming, Event: &event, RefreshInSeconds: 30}
+
+	ticked := event
+	ticked.SecondsUntilStart, ticked.PercentElapsed = 59, 0.5
+	cur := types.NextEventResponse{State: types.StateUpcoming, Event: &ticked, RefreshInSeconds: 29}
+	if Changed(prev, cur) || DisplayKey(prev) != DisplayKey(cur) {
+		t.Error("a countdown tick changed the display")
+	}
+
+	warned := ticked
+	warned.FiveMinuteWarning = true
+	cur.Event = &warned
+	if !Changed(prev, cur) || DisplayKey(prev) == DisplayKey(cur) {
+		t.Error("a warning did not change the display")
+	}
+
+	if !Changed(prev, types.NextEventResponse{State: types.StateIdle}) {
+		t.Error("going idle did not change the display")
+	}
+}
+
+func TestDisplayKeyCoversDisplayedFields(t *testing.T) {
+	room := "Room 1"
+	event := types.Event{
+		Name: "Standup", StartTime: 1000, EndTime: 2000, Location: &room,
+		Colors: types.Colors{Foreground: "#FFFFFF", Background: "#000000"},
+		Lines:  map[string][]string{"tb-8": {"Standup"}, "tom-thumb": {"Standup"}},
+	}
+	key := DisplayKey(types.NextEventResponse{State: types.StateUpcoming, Event: &event})
+
+	tests := []struct {
+		name   string
+		modify func(*types.Event)
+	}{
+		{name: "location", modify: func(e *types.Event) { other := "Room 2"; e.Location = &other }},
+		{name: "no location", modify: func(e *types.Event) { e.Location = nil }},
+		{name: "foreground", modify: func(e *types.Event) { e.Colors.Foreground = "#FF0000" }},
+		{name: "background", modify: func(e *types.Event) { e.Colors.Background = "#200000" }},
+		{name: "lines", modify: func(e *types.Event) { e.Lines = map[string][]string{"tb-8": {"Stand", "up"}} }},
+		{name: "categories", modify: func(e *types.Event) { e.Categories = []string{"work"} }},
+		{name: "end", modify: func(e *types.Event) { e.EndTime = 2400 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := event
+			test.modify(&changed)
+			if DisplayKey(types.NextEventResponse{State: types.StateUpcoming, Event: &changed}) == key {
+				t.Errorf("changing the %s kept the display key", test.name)
+			}
+		})
+	}
+
+	if DisplayKey(types.NextEventResponse{State: types.StateUpcoming, Event: &event, FilteredOut: 2}) == key {
+		t.Error("changing filteredOut kept the display key")
+	}
+	if DisplayKey(types.NextEventResponse{State: types.StateUpcoming, Event: &event, RefreshAt: 1, WindowEnd: 9}) != key {
+		t.Error("refresh hints changed the display key")
+	}
+}
@@ -0,0 +1,108 @@
+package calendar
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/apognu/gocal"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/theme"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// properties holds the VCALENDAR level properties that gocal does not expose
+type properties map[string]string
+
+// calendarProperties reads the unfolded properties that precede the first component
+func calendarProperties(data string) properties {
+	props := properties{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if len(lines) > 0 {
+				lines[len(lines)-1] += line[1:]
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "BEGIN:") && line != "BEGIN:VCALENDAR" {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";")
+		props[strings.ToUpper(name)] = strings.TrimSpace(value)
+	}
+
+	return props
+}
+
+// name returns the display name of the calendar
+func (p properties) name() string {
+	if name := p["X-WR-CALNAME"]; name != "" {
+		return name
+	}
+	return p["NAME"]
+}
+
+// color returns the color the feed declares for an event, preferring the
+// event's own RFC 7986 COLOR over the calendar wide colors
+func (p properties) color(e gocal.Event) string {
+	if color := e.CustomAttributes["COLOR"]; color != "" {
+		return color
+	}
+	if color := p["COLOR"]; color != "" {
+		return color
+	}
+	return p["X-APPLE-CALENDAR-COLOR"]
+}
+
+// Colorize resolves the foreground and background colors of an event. The feed's
+// own color is used first, falling back to a palette color for its first
+// category. Server rules and then request rules override both.
+func (c *Calendar) Colorize(e *t.Event, rules []t.ColorRule) {
+	base, err := theme.Parse(e.Colors.Source)
+	found := err == nil
+	if !found && len(e.Categories) > 0 {
+		base, found = theme.ForCategory(e.Categories[0]), true
+	}
+
+	for _, rule := range append(append([]t.ColorRule{}, c.settings().ColorRules...), rules...) {
+		if !ruleMatches(rule, *e) {
+			continue
+		}
+		if color, err := theme.Parse(rule.Color); err == nil {
+			base, found = color, true
+		}
+	}
+
+	palette := theme.Default
+	if found {
+		palette = theme.Readable(base)
+	}
+	e.Colors.Foreground = palette.Foreground.Hex()
+	e.Colors.Background = palette.Background.Hex()
+}
+
+// ruleMatches reports whether every condition set on the rule holds for the event
+func ruleMatches(rule t.ColorRule, e t.Event) bool {
+	if rule.Calendar != "" && !strings.EqualFold(rule.Calendar, e.Calendar) {
+		return false
+	}
+	if rule.Category == "" {
+		return true
+	}
+	for _, category := range e.Categories {
+		if strings.EqualFold(strings.TrimSpace(category), rule.Category) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package calendar
+
+import (
+	"strconv"
+
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// Key identifies an event across downloads of its feed. Events without a UID
+// fall back to their name and start, a change to either then reads as the
+// event being cancelled.
+func Key(e t.Event) string {
+	if e.UID == "" {
+		return e.Name + "@" + strconv.FormatInt(e.StartTime, 10)
+	}
+	if e.RecurrenceID != 0 {
+		return e.UID + "@" + strconv.FormatInt(e.RecurrenceID, 10)
+	}
+	return e.UID
+}
+
+// Transitions compares two looks at a feed, taken at since and now, and reports
+// what happened to its events in between: warning thresholds and starts and ends
+// that fell in the interval, events that moved and events that went away or
+// were marked cancelled.
+func Transitions(prev, cur []t.Event, since, now int64) []t.Transition {
+	previous := make(map[string]t.Event, len(prev))
+	for _, e := range prev {
+		previous[Key(e)] = e
+	}
+
+	crossed := func(at int64) bool { return at > since && at <= now }
+
+	var transitions []t.Transition
+	seen := make(map[string]bool, len(cur))
+	for _, e := range cur {
+		key := Key(e)
+		seen[key] = true
+
+		p, existed := previous[key]
+		switch {
+		case e.Cancelled && existed && !p.Cancelled:
+			transitions = append(transitions, t.Transition{Kind: t.TransitionCancelled, At: now, Event: e, Previous: &p})
+			continue
+		case e.Cancelled:
+			continue
+		case existed && (p.StartTime != e.StartTime || p.EndTime != e.EndTime):
+			transitions = append(transitions, t.Transition{Kind: t.TransitionRescheduled, At: now, Event: e, Previous: &p})
+		}
+
+		for _, offset := range WarningOffsets {
+			if crossed(e.StartTime - offset) {
+				transitions = append(transitions, t.Transition{Kind: t.TransitionThreshold, Threshold: offset, At: e.StartTime - offset, Event: e})
+			}
+		}
+		if crossed(e.StartTime) {
+			transitions = append(transitions, t.Transition{Kind: t.TransitionStarted, At: e.StartTime, Event: e})
+		}
+		if crossed(e.EndTime) {
+			transitions = append(transitions, t.Transition{Kind: t.TransitionEnded, At: e.EndTime, Event: e})
+		}
+	}
+
+	// events drop out of the feed once they end, anything else that went away
+	// was cancelled
+	for _, p := range prev {
+		if seen[Key(p)] || p.Cancelled {
+			continue
+		}
+		if p.EndTime <= now {
+			if crossed(p.EndTime) {
+				transitions = append(transitions, t.Transition{Kind: t.TransitionEnded, At: p.EndTime, Event: p})
+			}
+			continue
+		}
+		transitions = append(transitions, t.Transition{Kind: t.TransitionCancelled, At: now, Event: p})
+	}
+
+	return transitions
+}
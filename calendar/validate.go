@@ -0,0 +1,31 @@
+package calendar
+
+import (
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/openapi"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/theme"
+)
+
+// NewValidator returns a validator that checks requests against the schemas
+// of schemas, with the formats calendar requests declare. Every API checks
+// its requests with one, so they accept the same requests.
+func NewValidator(schemas *openapi.Generator) *openapi.Validator {
+	return &openapi.Validator{
+		Generator: schemas,
+		Formats: map[string]openapi.FormatChecker{
+			"uri":     CheckFeedURL,
+			"iana-tz": CheckTZ,
+			"filter":  checkFilter,
+			"color":   checkColor,
+		},
+	}
+}
+
+func checkFilter(value string) error {
+	_, err := ParseFilter(value)
+	return err
+}
+
+func checkColor(value string) error {
+	_, err := theme.Parse(value)
+	return err
+}
@@ -0,0 +1,17 @@
+package main
+
+import "github.com/quesurifn/ics-calendar-tidbyt-server/server"
+
+// appConfig starts from the defaults of the HTTP server, so a feed resolves
+// the same time zones and limits over gRPC as over REST. It reads the same
+// config.yml, set ICS_GRPC_PORT or --port to serve both from one file. Its
+// TLS, ClientIdentities, JWT, APIKeys and RateLimit sections guard the
+// service like the calendar routes.
+var appConfig = defaultConfig()
+
+func defaultConfig() server.Config {
+	config := server.DefaultConfig()
+	config.AppName = "Tidbyt ICS gRPC Server"
+	config.Port = "9090"
+	return config
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/apikey"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/config"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ratelimit"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/redact"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/tlsconfig"
+	calendarv1 "github.com/quesurifn/ics-calendar-tidbyt-server/proto/calendar/v1"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/rpc"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/server"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var cfg *config.Config
+
+var serverCmd = &cobra.Command{
+	Use:   "isc-grpc",
+	Short: "Run the ICS gRPC server",
+	Run: func(cmd *cobra.Command, args []string) {
+		logConfig := zap.NewProductionConfig()
+		if cfg.Debug {
+			// Event details are only logged at debug level
+			logConfig.Level.SetLevel(zap.DebugLevel)
+		}
+		logger, _ := logConfig.Build(zap.WrapCore(redact.WrapCore))
+
+		cal := c.Calendar{
+			Logger:   logger,
+			Settings: server.CalendarSettings(appConfig),
+		}
+
+		srv, err := newServer(logger, appConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		calendarv1.RegisterCalendarServiceServer(srv, rpc.CalendarServer{
+			Logger:   logger,
+			Calendar: &cal,
+		})
+
+		defer func() {
+			err := logger.Sync()
+			if err != nil && !errors.Is(err, syscall.ENOTTY) {
+				logger.Fatal(err.Error())
+			}
+		}()
+
+		lis, err := net.Listen("tcp", net.JoinHostPort(appConfig.Host, appConfig.Port))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		logger.Info("Serving gRPC", zap.String("addr", lis.Addr().String()), zap.Bool("tls", appConfig.TLS.Enabled()))
+		log.Fatal(srv.Serve(lis))
+	},
+}
+
+// newServer builds a gRPC server guarded like the calendar routes of the
+// HTTP server: TLS when a certificate is set, client certificates, API keys
+// or bearer tokens when configured, and the rate limits
+func newServer(logger *zap.Logger, config server.Config) (*grpc.Server, error) {
+	guard := &rpc.Guard{
+		Logger:      logger,
+		ClientCerts: config.TLS.ClientCA != "",
+		Identities:  config.ClientIdentities,
+	}
+	if config.APIKeys.Store != "" {
+		keys, err := apikey.NewStore(config.APIKeys.Store)
+		if err != nil {
+			return nil, err
+		}
+		guard.Keys = keys
+	}
+	if config.JWT.PublicKey != "" {
+		keys, err := auth.NewKeySet(config.JWT.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		guard.Verifier = &auth.Verifier{
+			Keys:     keys,
+			Audience: config.JWT.Audience,
+			Issuer:   config.JWT.Issuer,
+		}
+	}
+	limiter, err := ratelimit.New(config.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	guard.Limiter = limiter
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(guard.Unary()),
+		grpc.ChainStreamInterceptor(guard.Stream()),
+	}
+	if config.TLS.Enabled() {
+		tlsConfig, err := tlsconfig.New(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	} else if config.TLS.ClientCA == "" && guard.Keys == nil && guard.Verifier == nil {
+		logger.Warn("Serving gRPC", zap.String("err", "no TLS and no credentials configured, every client is trusted"))
+	}
+	return grpc.NewServer(opts...), nil
+}
+
+func init() {
+	cfg = config.New(&config.Settings{ENVPrefix: "ICS_GRPC"})
+
+	serverCmd.Flags().StringVarP(&appConfig.Port, "port", "p", appConfig.Port, "gRPC server port")
+	serverCmd.Flags().BoolVarP(&cfg.Debug, "debug", "d", cfg.Debug, "Debug Mode")
+}
+
+func main() {
+	if err := cfg.Load(&appConfig, "config.yml"); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(-1)
+	}
+
+	if err := serverCmd.Execute(); err != nil {
+		os.Stderr.WriteString(err.Error())
+		os.Exit(-1)
+	}
+}
@@ -1,14 +1,5 @@
 package main
 
-var appConfig = struct {
-	AppName string
+import "github.com/quesurifn/ics-calendar-tidbyt-server/server"
 
-	Port string
-	Env  string
-	JWT  struct {
-		PublicKey string
-	}
-}{
-	AppName: "Tidbyt ICS Server",
-	Port:    "8080",
-}
+var appConfig = server.DefaultConfig()
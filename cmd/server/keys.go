@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/apikey"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	h "github.com/quesurifn/ics-calendar-tidbyt-server/handlers"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/sliceutil"
+	"github.com/spf13/cobra"
+)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage API keys",
+}
+
+var keyFlags = struct {
+	Name      string
+	Scopes    []string
+	ExpiresIn time.Duration
+}{}
+
+var keysCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an API key, it is printed once",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if keyFlags.Name == "" {
+			return errors.New("--name is required")
+		}
+		if len(keyFlags.Scopes) == 0 {
+			return errors.New("at least one --scope is required")
+		}
+		for _, scope := range keyFlags.Scopes {
+			if !sliceutil.ContainsString(auth.Scopes, scope) {
+				return fmt.Errorf("unknown scope %q, expected one of %s", scope, strings.Join(auth.Scopes, ", "))
+			}
+		}
+
+		keys, err := keyStore()
+		if err != nil {
+			return err
+		}
+		var expiresAt int64
+		if keyFlags.ExpiresIn > 0 {
+			expiresAt = time.Now().Add(keyFlags.ExpiresIn).Unix()
+		}
+		key, secret, err := keys.Create(keyFlags.Name, keyFlags.Scopes, expiresAt)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("ID:  %s\nKey: %s\n\nThe key is not shown again, send it in the %s header.\n", key.ID, secret, h.APIKeyHeader)
+		return nil
+	},
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API keys",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := keyStore()
+		if err != nil {
+			return err
+		}
+		list, err := keys.Keys()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCOPES\tCREATED\tLAST USED\tEXPIRES")
+		for _, key := range list {
+			expires := formatTime(key.ExpiresAt)
+			if key.Expired(time.Now()) {
+				expires += " (expired)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, strings.Join(key.Scopes, ","),
+				formatTime(key.CreatedAt), formatTime(key.LastUsedAt), expires)
+		}
+		return w.Flush()
+	},
+}
+
+var keysRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke an API key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := keyStore()
+		if err != nil {
+			return err
+		}
+		return keys.Revoke(args[0])
+	},
+}
+
+func init() {
+	keysCreateCmd.Flags().StringVarP(&keyFlags.Name, "name", "n", "", "name of the client the key is for")
+	keysCreateCmd.Flags().StringSliceVarP(&keyFlags.Scopes, "scope", "s", nil, "scope the key grants, repeatable: "+strings.Join(auth.Scopes, ", "))
+	keysCreateCmd.Flags().DurationVar(&keyFlags.ExpiresIn, "expires-in", 0, "how long the key works, e.g. 720h, forever when unset")
+
+	keysCmd.AddCommand(keysCreateCmd, keysListCmd, keysRevokeCmd)
+	serverCmd.AddCommand(keysCmd)
+}
+
+// keyStore opens the API key store of the configuration
+func keyStore() (*apikey.Store, error) {
+	if appConfig.APIKeys.Store == "" {
+		return nil, errors.New("apikeys.store is not configured")
+	}
+	return apikey.NewStore(appConfig.APIKeys.Store)
+}
+
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).Format(time.RFC3339)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/export"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/jsonfile"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/webhook"
+	"github.com/spf13/cobra"
+)
+
+var reencryptCmd = &cobra.Command{
+	Use:   "reencrypt",
+	Short: "Encrypt the stores again with the first master key",
+	Long: "Encrypt the stores again with the first master key. To rotate, put the new key first in the " +
+		"config of every server and restart them, a server seals its stores with the new key as it starts. " +
+		"Then run reencrypt for stores no server opens, and remove the old key last. " +
+		"It refuses to run on a store a running server holds, which would seal it with its own first key again.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := appConfig.Encryption.Keyring()
+		if err != nil {
+			return err
+		}
+		if keys == nil {
+			return errors.New("encryption.keys or encryption.keyfile is not configured")
+		}
+
+		if path := appConfig.Webhooks.Store; path != "" {
+			webhooks, err := webhook.NewStore(path, keys)
+			if err != nil {
+				return inUse(err)
+			}
+			defer webhooks.Close()
+			if err := webhooks.Reencrypt(); err != nil {
+				return err
+			}
+			fmt.Println("Re-encrypted", path)
+		}
+		if path := appConfig.Exports.Store; path != "" {
+			exports, err := export.NewStore(path, keys)
+			if err != nil {
+				return inUse(err)
+			}
+			defer exports.Close()
+			if err := exports.Reencrypt(); err != nil {
+				return err
+			}
+			fmt.Println("Re-encrypted", path)
+		}
+		return nil
+	},
+}
+
+// inUse explains the lock error of a store a server holds
+func inUse(err error) error {
+	if errors.Is(err, jsonfile.ErrLocked) {
+		return fmt.Errorf("%w: a running server holds it, restart the server with the new key first instead", err)
+	}
+	return err
+}
+
+func init() {
+	serverCmd.AddCommand(reencryptCmd)
+}
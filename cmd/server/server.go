@@ -1,18 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"log"
 	"os"
+	"os/signal"
 	"syscall"
-	"time"
 
-	"github.com/gofiber/contrib/fiberzap/v2"
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
-	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
-	h "github.com/quesurifn/ics-calendar-tidbyt-server/handlers"
 	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/config"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/server"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -23,66 +20,93 @@ var serverCmd = &cobra.Command{
 	Use:   "isc-srv",
 	Short: "Run the ICS server",
 	Run: func(cmd *cobra.Command, args []string) {
-		app := fiber.New()
-		logger, _ := zap.NewProduction()
-		fiberLogger := fiberzap.New(fiberzap.Config{
-			Logger: logger,
-		})
-		fiberLimiter := limiter.New(limiter.Config{
-			Next: func(c *fiber.Ctx) bool {
-				return c.IP() == "127.0.0.1"
-			},
-			Max:        20,
-			Expiration: 30 * time.Second,
-			KeyGenerator: func(c *fiber.Ctx) string {
-				return c.Get("x-forwarded-for")
-			},
-			LimitReached: func(c *fiber.Ctx) error {
-				return c.JSON(fiber.Map{
-					"error": "Too many requests",
-				})
-			},
-		})
-
-		app.Use(fiberLimiter)
-		app.Use(fiberLogger)
-
-		cal := c.Calendar{
-			Logger: logger,
-			TZMap: map[string]string{
-				"Hawaii Standard Time":     "Pacific/Honolulu",
-				"Alaskan Standard Time":    "America/Anchorage",
-				"Alaskan Daylight Time":    "America/Anchorage",
-				"SA Pacific Standard Time": "America/Bogota",
-				"Pacific Standard Time":    "America/Los_Angeles",
-				"Pacific Daylight Time":    "America/Los_Angeles",
-				"Central Standard Time":    "America/Chicago",
-				"Central Daylight Time":    "America/Chicago",
-				"Mountain Standard Time":   "America/Denver",
-				"Mountain Daylight Time":   "America/Denver",
-				"Eastern Standard Time":    "America/New_York",
-				"Eastern Daylight Time":    "America/New_York",
-			},
+		if cfg.Debug {
+			// Event details are only logged at debug level
+			appConfig.Log.Level = "debug"
 		}
-		h := h.Handlers{
-			Logger:   logger,
-			Calendar: &cal,
+		srv, err := server.New(appConfig)
+		if err != nil {
+			log.Fatal(err)
 		}
-
-		app.Get("/", h.RootHandler)
-		app.Post("/ics/next-event", h.NextEventHandler)
-
+		logger := srv.Logger
 		defer func() {
+			// Syncing a terminal or pipe fails, there is nothing to flush
 			err := logger.Sync()
-			if err != nil && !errors.Is(err, syscall.ENOTTY) {
+			if err != nil && !errors.Is(err, syscall.ENOTTY) && !errors.Is(err, syscall.EINVAL) {
 				logger.Fatal(err.Error())
 			}
 		}()
 
-		log.Fatal(app.Listen(":3000"))
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Changes to config.yml are applied while the server runs, flags
+		// still override them
+		reloads, reloadErrors := watchConfig(logger)
+		go func() {
+			for {
+				select {
+				case next := <-reloads:
+					if cmd.Flags().Changed("port") {
+						next.Port = appConfig.Port
+					}
+					if cfg.Debug {
+						next.Log.Level = "debug"
+					}
+					_ = srv.Reload(next)
+				case err := <-reloadErrors:
+					logger.Error("Config reload failed", zap.Error(err))
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		if err := srv.Run(ctx); err != nil {
+			logger.Fatal("Server", zap.Error(err))
+		}
 	},
 }
 
+// watchConfig loads config.yml once more with a watcher, which sends the
+// configuration whenever the file changes. Only the server watches, the other
+// commands load the configuration once and exit. The channels hold the latest
+// value, so the watcher never waits for a reader that is gone.
+func watchConfig(logger *zap.Logger) (<-chan server.Config, <-chan error) {
+	reloads, reloadErrors := make(chan server.Config, 1), make(chan error, 1)
+	watcher := config.New(&config.Settings{
+		ENVPrefix:  "ICS_SRV",
+		AutoReload: true,
+		AutoReloadCallback: func(config interface{}) {
+			offer(reloads, *config.(*server.Config))
+		},
+		AutoReloadErrorCallback: func(err error) {
+			offer(reloadErrors, err)
+		},
+	})
+
+	watched := server.DefaultConfig()
+	if err := watcher.Load(&watched, "config.yml"); err != nil {
+		logger.Error("Config reload failed", zap.Error(err))
+	}
+	return reloads, reloadErrors
+}
+
+// offer puts v in a channel with a buffer of one, replacing the value the
+// reader has not taken yet
+func offer[T any](ch chan T, v T) {
+	for {
+		select {
+		case ch <- v:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
 func init() {
 	cfg = config.New(&config.Settings{ENVPrefix: "ICS_SRV"})
 
@@ -0,0 +1,178 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"time"
+
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ical"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+const (
+	// prodID names the server in exported calendars
+	prodID = "-//Tidbyt ICS Server//Export//EN"
+	// uidDomain makes exported UIDs globally unique as RFC 5545 asks
+	uidDomain = "tidbyt-ics"
+	// redactedName replaces event names when a feed is redacted
+	redactedName = "Busy"
+)
+
+// Feed merges and filters upstream feeds into a calendar that is served
+// under an opaque token instead of the upstream URLs
+type Feed struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name" maxLength:"128" doc:"Calendar name shown by subscribing apps"`
+	Sources    []string `json:"sources" required:"true" doc:"Upstream ICS feed URLs, never exposed by the export"`
+	Filter     string   `json:"filter" format:"filter" maxLength:"512" doc:"Event filter applied to every source"`
+	TZ         string   `json:"tz" format:"iana-tz" doc:"Time zone events are written in, UTC when empty"`
+	WindowDays int      `json:"windowDays" minimum:"1" maximum:"31" doc:"Days ahead to export"`
+	Redact     bool     `json:"redact" doc:"Export every event as Busy, without location or categories"`
+	TokenHash  string   `json:"tokenHash,omitempty"`
+	CreatedAt  int64    `json:"createdAt"`
+}
+
+// Created is returned once when a feed is added, it is the only time the token is shown
+type Created struct {
+	Feed  Feed   `json:"feed"`
+	Token string `json:"token"`
+	Path  string `json:"path" doc:"Where the feed is served, relative to the server"`
+}
+
+// Redacted returns the feed without its token hash
+func (f Feed) Redacted() Feed {
+	f.TokenHash = ""
+	return f
+}
+
+// withSources returns the feed with f applied to its sources, the store seals
+// them with it before writing and opens them after reading
+func (f Feed) withSources(fn func(string) (string, error)) (Feed, error) {
+	sources := make([]string, len(f.Sources))
+	for i, source := range f.Sources {
+		value, err := fn(source)
+		if err != nil {
+			return Feed{}, err
+		}
+		sources[i] = value
+	}
+	f.Sources = sources
+	return f, nil
+}
+
+// Events downloads every source of the feed and returns their merged events
+// in start order
+func Events(cal *c.Calendar, feed Feed) ([]t.Event, error) {
+	req := t.IcsRequest{ShowInProgress: true, Filter: feed.Filter, TZ: feed.TZ, WindowDays: feed.WindowDays}
+
+	var events []t.Event
+	for _, source := range feed.Sources {
+		req.ICSUrl = source
+		kept, _, err := cal.Events(req)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, kept...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime < events[j].StartTime
+	})
+	return events, nil
+}
+
+// Write serializes events as an RFC 5545 calendar. Times are written in the
+// feed's time zone, described by a VTIMEZONE covering the events.
+func Write(w io.Writer, feed Feed, events []t.Event, now time.Time) error {
+	loc := time.UTC
+	if feed.TZ != "" {
+		var err error
+		if loc, err = time.LoadLocation(feed.TZ); err != nil {
+			return err
+		}
+	}
+
+	out := ical.NewWriter(w)
+	out.Begin("VCALENDAR")
+	out.Property("VERSION", "2.0")
+	out.Property("PRODID", prodID)
+	out.Property("CALSCALE", "GREGORIAN")
+	out.Property("METHOD", "PUBLISH")
+	if feed.Name != "" {
+		out.Property("X-WR-CALNAME", ical.Text(feed.Name))
+	}
+
+	formatTime := func(unix int64) (string, []ical.Param) {
+		return ical.UTC(time.Unix(unix, 0)), nil
+	}
+	if loc != time.UTC {
+		out.Property("X-WR-TIMEZONE", loc.String())
+		from, to := now, now
+		for _, e := range events {
+			from = minTime(from, time.Unix(e.StartTime, 0))
+			to = maxTime(to, time.Unix(e.EndTime, 0))
+		}
+		out.Timezone(loc, from.AddDate(0, 0, -1), to.AddDate(0, 0, 1))
+
+		tzid := []ical.Param{{Name: "TZID", Value: loc.String()}}
+		formatTime = func(unix int64) (string, []ical.Param) {
+			return ical.Local(time.Unix(unix, 0).In(loc)), tzid
+		}
+	}
+
+	for _, e := range events {
+		out.Begin("VEVENT")
+		out.Property("UID", UID(e))
+		out.Property("DTSTAMP", ical.UTC(now))
+		start, params := formatTime(e.StartTime)
+		out.Property("DTSTART", start, params...)
+		end, params := formatTime(e.EndTime)
+		out.Property("DTEND", end, params...)
+
+		if feed.Redact {
+			out.Property("SUMMARY", redactedName)
+			out.Property("CLASS", "PRIVATE")
+		} else {
+			out.Property("SUMMARY", ical.Text(e.Name))
+			if e.Location != nil && *e.Location != "" {
+				out.Property("LOCATION", ical.Text(*e.Location))
+			}
+			if len(e.Categories) > 0 {
+				out.Property("CATEGORIES", ical.List(e.Categories))
+			}
+		}
+		out.Property("TRANSP", "OPAQUE")
+		if e.Cancelled {
+			out.Property("STATUS", "CANCELLED")
+		}
+		out.End("VEVENT")
+	}
+
+	out.End("VCALENDAR")
+	return out.Flush()
+}
+
+// UID derives a stable UID from the upstream identity of an event, so
+// subscribers see the same event across refreshes without learning the
+// upstream UID
+func UID(e t.Event) string {
+	sum := sha256.Sum256([]byte(e.Calendar + "\x00" + c.Key(e)))
+	return hex.EncodeToString(sum[:16]) + "@" + uidDomain
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
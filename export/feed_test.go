@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"go.uber.org/zap"
+)
+
+const icsTime = "20060102T150405Z"
+
+// feedServer serves an ICS feed with an event in each room, an hour apart
+func feedServer(t *testing.T, rooms ...string) *httptest.Server {
+	t.Helper()
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n")
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Minute)
+	stamp := start.Format(icsTime)
+	for i, room := range rooms {
+		from := start.Add(time.Duration(i) * time.Hour)
+		fmt.Fprintf(&b, "BEGIN:VEVENT\r\nUID:event-%d\r\nDTSTAMP:%s\r\nSUMMARY:Meeting %d\r\nLOCATION:%s\r\nDTSTART:%s\r\nDTEND:%s\r\nEND:VEVENT\r\n",
+			i, stamp, i, room, from.Format(icsTime), from.Add(30*time.Minute).Format(icsTime))
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		_, _ = w.Write([]byte(b.String()))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWriteKeepsLocationOfEveryEvent(t *testing.T) {
+	server := feedServer(t, "Room A", "Room B", "Room C")
+	cal := &c.Calendar{Logger: zap.NewNop()}
+	feed := Feed{Sources: []string{server.URL}}
+
+	events, err := Events(cal, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, feed, events, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var locations []string
+	for _, line := range strings.Split(out.String(), "\r\n") {
+		if strings.HasPrefix(line, "LOCATION:") {
+			locations = append(locations, strings.TrimPrefix(line, "LOCATION:"))
+		}
+	}
+	want := []string{"Room A", "Room B", "Room C"}
+	if strings.Join(locations, "|") != strings.Join(want, "|") {
+		t.Errorf("got locations %q, want %q", locations, want)
+	}
+}
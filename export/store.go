@@ -0,0 +1,189 @@
+package export
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/envelope"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/jsonfile"
+)
+
+var ErrNotFound = errors.New("export not found")
+
+// Store holds the export feeds. Tokens are only kept as hashes, a token is
+// handed out once when its feed is added. Sources are encrypted in the file
+// with keys.
+type Store struct {
+	path   string
+	keys   *envelope.Keyring
+	unlock func() error
+
+	mu    sync.RWMutex
+	feeds map[string]Feed
+}
+
+// NewStore creates a store that persists to the JSON file at path, an empty
+// path keeps the feeds in memory. Like webhook.NewStore it holds the lock of
+// the file until Close and seals sources sealed with an old key again.
+func NewStore(path string, keys *envelope.Keyring) (*Store, error) {
+	s := &Store{path: path, keys: keys, feeds: map[string]Feed{}}
+	if path == "" {
+		return s, nil
+	}
+
+	unlock, err := jsonfile.Lock(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.unlock = unlock
+	if err := s.load(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the file of the store and seals stale sources again
+func (s *Store) load() error {
+	var feeds []Feed
+	if err := jsonfile.Load(s.path, &feeds); err != nil {
+		return err
+	}
+	stale := false
+	for _, feed := range feeds {
+		for _, source := range feed.Sources {
+			stale = stale || !s.keys.Current(source)
+		}
+		opened, err := feed.withSources(s.keys.Open)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", feed.ID, err)
+		}
+		s.feeds[feed.ID] = opened
+	}
+
+	if stale {
+		return s.save()
+	}
+	return nil
+}
+
+// Close releases the lock of the file
+func (s *Store) Close() error {
+	if s.unlock == nil {
+		return nil
+	}
+	return s.unlock()
+}
+
+// Add saves a new feed and returns it along with the token it is served under
+func (s *Store) Add(feed Feed) (Feed, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return Feed{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feed.ID = uuid.NewString()
+	feed.TokenHash = hashToken(token)
+	feed.CreatedAt = time.Now().Unix()
+	s.feeds[feed.ID] = feed
+
+	return feed, token, s.save()
+}
+
+// Remove deletes a feed, its token stops working straight away
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.feeds[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.feeds, id)
+
+	return s.save()
+}
+
+// Feeds returns every feed, oldest first
+func (s *Store) Feeds() []Feed {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	feeds := make([]Feed, 0, len(s.feeds))
+	for _, feed := range s.feeds {
+		feeds = append(feeds, feed)
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		if feeds[i].CreatedAt != feeds[j].CreatedAt {
+			return feeds[i].CreatedAt < feeds[j].CreatedAt
+		}
+		return feeds[i].ID < feeds[j].ID
+	})
+
+	return feeds
+}
+
+// ByToken returns the feed served under a token
+func (s *Store) ByToken(token string) (Feed, error) {
+	hash := hashToken(token)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, feed := range s.feeds {
+		if feed.TokenHash == hash {
+			return feed, nil
+		}
+	}
+	return Feed{}, ErrNotFound
+}
+
+// Reencrypt writes the store again, sealing every source with the current
+// master key
+func (s *Store) Reencrypt() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save()
+}
+
+// save writes the store to its file, the caller holds the lock
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	feeds := make([]Feed, 0, len(s.feeds))
+	for _, feed := range s.feeds {
+		sealed, err := feed.withSources(s.keys.Seal)
+		if err != nil {
+			return err
+		}
+		feeds = append(feeds, sealed)
+	}
+	return jsonfile.Save(s.path, feeds)
+}
+
+// newToken returns 256 random bits, URL safe
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,75 @@
+package gql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.uber.org/zap"
+)
+
+var (
+	errNoSources   = errors.New("at least one source is required")
+	errBadArgument = errors.New("invalid argument")
+)
+
+// argumentErrors are written for clients and shown as they are
+var argumentErrors = []error{errNoSources, errBadArgument, errBadCursor}
+
+// calendarErrors maps calendar errors to the codes and messages the REST API
+// reports them with, upstream details stay in the logs
+var calendarErrors = []struct {
+	err     error
+	code    t.ErrorCode
+	message string
+}{
+	{c.ErrFeedUnreachable, t.ErrFeedUnreachable, "The calendar feed could not be downloaded"},
+	{c.ErrFeedNotICS, t.ErrFeedNotICS, "The calendar feed is not a valid ICS file"},
+	{c.ErrBadFilter, t.ErrValidation, "The filter is invalid"},
+	{c.ErrBadTZ, t.ErrBadTZ, "Unknown time zone, use an IANA name such as America/Chicago"},
+}
+
+// presentError reports resolver errors with the code and message of the
+// REST API. Parse, validation and complexity errors wrap no other error and
+// are passed through, they only describe the query.
+func presentError(logger *zap.Logger) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		if gqlErr.Err == nil {
+			return gqlErr
+		}
+
+		code, message := classify(gqlErr.Err)
+		if code == t.ErrInternal {
+			logger.Error("presentError", zap.Error(err))
+		} else {
+			logger.Info("presentError", zap.Error(err))
+		}
+
+		return &gqlerror.Error{
+			Err:        gqlErr.Err,
+			Message:    message,
+			Path:       gqlErr.Path,
+			Locations:  gqlErr.Locations,
+			Extensions: map[string]interface{}{"code": code},
+		}
+	}
+}
+
+// classify returns the code and client facing message of a resolver error
+func classify(err error) (t.ErrorCode, string) {
+	for _, argErr := range argumentErrors {
+		if errors.Is(err, argErr) {
+			return t.ErrValidation, err.Error()
+		}
+	}
+	for _, mapping := range calendarErrors {
+		if errors.Is(err, mapping.err) {
+			return mapping.code, mapping.message
+		}
+	}
+	return t.ErrInternal, "Internal server error"
+}
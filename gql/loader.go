@@ -0,0 +1,75 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+type loaderKey struct{}
+
+// loader downloads each feed URL at most once for the lifetime of a request
+type loader struct {
+	calendar *c.Calendar
+
+	mu    sync.Mutex
+	feeds map[string]*feed
+}
+
+type feed struct {
+	once sync.Once
+	data string
+	err  error
+}
+
+func withLoader(ctx context.Context, calendar *c.Calendar) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &loader{calendar: calendar, feeds: map[string]*feed{}})
+}
+
+func loaderFor(ctx context.Context) *loader {
+	return ctx.Value(loaderKey{}).(*loader)
+}
+
+// download returns the body of a feed, sharing the result with every other
+// caller asking for the same URL. Aliased calendars may name maxSources
+// distinct feeds between them.
+func (l *loader) download(url string) (string, error) {
+	l.mu.Lock()
+	f, ok := l.feeds[url]
+	if !ok {
+		if len(l.feeds) >= maxSources {
+			l.mu.Unlock()
+			return "", fmt.Errorf("%w: at most %d distinct sources may be read by one request", errBadArgument, maxSources)
+		}
+		f = &feed{}
+		l.feeds[url] = f
+	}
+	l.mu.Unlock()
+
+	f.once.Do(func() {
+		f.data, f.err = l.calendar.DownloadCalendar(url)
+	})
+	return f.data, f.err
+}
+
+// events parses the feeds of a calendar and merges their events
+func (l *loader) events(cal *Calendar) ([]t.Event, error) {
+	var events []t.Event
+	for _, source := range cal.Sources {
+		data, err := l.download(source)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := l.calendar.ParseCalendar(data, cal.TZ, cal.Window)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, parsed...)
+	}
+
+	return events, nil
+}
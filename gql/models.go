@@ -0,0 +1,79 @@
+package gql
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+const (
+	// cursorPrefix marks the offsets encoded in event cursors
+	cursorPrefix = "offset:"
+	// maxSources is how many feeds one calendar may read together, each one
+	// is downloaded for every query
+	maxSources = 10
+)
+
+var errBadCursor = errors.New("invalid cursor")
+
+// Calendar is the parent of the calendar query fields, it carries the
+// arguments every field resolves its feeds with
+type Calendar struct {
+	Sources []string
+	TZ      string
+	Window  int
+}
+
+// request builds the calendar request a field resolves with
+func (cal *Calendar) request(filter *string) t.IcsRequest {
+	req := t.IcsRequest{TZ: cal.TZ, WindowDays: cal.Window}
+	if filter != nil {
+		req.Filter = *filter
+	}
+	return req
+}
+
+// encodeCursor returns the opaque cursor of the event after offset
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, errBadCursor
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, errBadCursor
+	}
+	return offset, nil
+}
+
+// checkArguments applies the checks the REST API runs on the feed URL, time
+// zone and window of a request to the arguments of a calendar
+func checkArguments(sources []string, tz *string, window *int) error {
+	var errs []error
+	if len(sources) > maxSources {
+		errs = append(errs, fmt.Errorf("%w: at most %d sources may be read together", errBadArgument, maxSources))
+	}
+	for i, source := range sources {
+		if err := c.CheckFeedURL(source); err != nil {
+			errs = append(errs, fmt.Errorf("%w: sources[%d] %v", errBadArgument, i, err))
+		}
+	}
+	if tz != nil && *tz != "" {
+		if err := c.CheckTZ(*tz); err != nil {
+			errs = append(errs, fmt.Errorf("%w: tz %v", errBadArgument, err))
+		}
+	}
+	if window != nil && (*window < 1 || *window > c.MaxLookaheadDays) {
+		errs = append(errs, fmt.Errorf("%w: window must be between 1 and %d days", errBadArgument, c.MaxLookaheadDays))
+	}
+	return errors.Join(errs...)
+}
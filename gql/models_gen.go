@@ -0,0 +1,32 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package gql
+
+import (
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+type EventConnection struct {
+	Edges       []*EventEdge `json:"edges"`
+	PageInfo    *PageInfo    `json:"pageInfo"`
+	TotalCount  int          `json:"totalCount"`
+	FilteredOut int          `json:"filteredOut"`
+}
+
+type EventEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   *types.Event `json:"node"`
+}
+
+type FontLines struct {
+	Font  string   `json:"font"`
+	Lines []string `json:"lines"`
+}
+
+type PageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor,omitempty"`
+}
+
+type Query struct {
+}
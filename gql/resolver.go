@@ -0,0 +1,42 @@
+package gql
+
+import (
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"go.uber.org/zap"
+)
+
+// This file will not be regenerated automatically.
+//
+// It serves as dependency injection for your app, add any dependencies you require here.
+
+// maxComplexity bounds the fields a query may select, so aliases cannot fan
+// one request out into any number of calendars. The fields of a calendar
+// count once for each of its sources, every source is parsed for them.
+const maxComplexity = 200
+
+type Resolver struct {
+	Logger *zap.Logger
+	ICS    *c.Calendar
+}
+
+// Handler serves the GraphQL schema. Every request gets its own feed loader so
+// a feed named by several fields of one query is only downloaded once, and
+// no request downloads more than maxSources feeds.
+func Handler(resolver *Resolver) http.Handler {
+	config := Config{Resolvers: resolver}
+	config.Complexity.Query.Calendar = func(childComplexity int, sources []string, tz *string, window *int) int {
+		return 1 + childComplexity*max(len(sources), 1)
+	}
+
+	srv := handler.NewDefaultServer(NewExecutableSchema(config))
+	srv.SetErrorPresenter(presentError(resolver.Logger))
+	srv.Use(extension.FixedComplexityLimit(maxComplexity))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.ServeHTTP(w, r.WithContext(withLoader(r.Context(), resolver.ICS)))
+	})
+}
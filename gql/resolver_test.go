@@ -0,0 +1,176 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+type response struct {
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func query(t *testing.T, q string) response {
+	logger := zap.NewNop()
+	handler := Handler(&Resolver{Logger: logger, ICS: &calendar.Calendar{Logger: logger}})
+
+	body, _ := json.Marshal(map[string]string{"query": q})
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var resp response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%v: %s", err, rec.Body)
+	}
+	return resp
+}
+
+func TestCalendarArguments(t *testing.T) {
+	tooMany := make([]string, maxSources+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%q", fmt.Sprintf("https://example.com/%d.ics", i))
+	}
+
+	tests := []struct {
+		name string
+		args string
+		want string
+	}{
+		{name: "scheme", args: `sources: ["ftp://example.com/cal.ics"]`, want: "sources[0] must use the http, https or webcal scheme"},
+		{name: "relative", args: `sources: ["/cal.ics"]`, want: "sources[0] must be an absolute URL"},
+		{name: "tz", args: `sources: ["https://example.com/cal.ics"], tz: "Mars/Olympus"`, want: "tz must be an IANA time zone"},
+		{name: "window", args: `sources: ["https://example.com/cal.ics"], window: 400`, want: "window must be between 1 and 31 days"},
+		{name: "sources", args: "sources: [" + strings.Join(tooMany, ",") + "]", want: "at most 10 sources"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := query(t, "{ calendar("+test.args+") { freeBusy { startTime } } }")
+			if len(resp.Errors) == 0 {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(resp.Errors[0].Message, test.want) {
+				t.Errorf("error %q does not contain %q", resp.Errors[0].Message, test.want)
+			}
+			if code := resp.Errors[0].Extensions["code"]; code != string(types.ErrValidation) {
+				t.Errorf("code = %v, want %s", code, types.ErrValidation)
+			}
+		})
+	}
+}
+
+func TestComplexityLimit(t *testing.T) {
+	var fields []string
+	for i := 0; i < maxComplexity; i++ {
+		fields = append(fields, fmt.Sprintf(`c%d: calendar(sources: ["https://example.com/cal.ics"]) { freeBusy { startTime } }`, i))
+	}
+
+	resp := query(t, "{ "+strings.Join(fields, " ")+" }")
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "complexity") {
+		t.Errorf("query over the complexity limit was not rejected: %+v", resp.Errors)
+	}
+}
+
+func TestErrorsHideUpstreamDetails(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	notICS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html>sign in</html>")
+	}))
+	defer notICS.Close()
+
+	tests := []struct {
+		name    string
+		source  string
+		code    types.ErrorCode
+		message string
+	}{
+		{name: "unreachable", source: closed.URL + "/cal.ics?token=s3cret", code: types.ErrFeedUnreachable,
+			message: "The calendar feed could not be downloaded"},
+		{name: "not ics", source: notICS.URL + "/cal.ics?token=s3cret", code: types.ErrFeedNotICS,
+			message: "The calendar feed is not a valid ICS file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := query(t, fmt.Sprintf(`{ calendar(sources: [%q]) { freeBusy { startTime } } }`, test.source))
+			if len(resp.Errors) != 1 {
+				t.Fatalf("errors = %+v, want one", resp.Errors)
+			}
+			if got := resp.Errors[0]; got.Message != test.message || got.Extensions["code"] != string(test.code) {
+				t.Errorf("error = %q %v, want %q %s", got.Message, got.Extensions["code"], test.message, test.code)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    types.ErrorCode
+		message string
+	}{
+		{err: fmt.Errorf("%w: dial tcp 10.0.0.1:443", calendar.ErrFeedUnreachable), code: types.ErrFeedUnreachable,
+			message: "The calendar feed could not be downloaded"},
+		{err: fmt.Errorf("%w: unknown field colour", calendar.ErrBadFilter), code: types.ErrValidation, message: "The filter is invalid"},
+		{err: calendar.ErrBadTZ, code: types.ErrBadTZ, message: "Unknown time zone, use an IANA name such as America/Chicago"},
+		{err: errBadCursor, code: types.ErrValidation, message: "invalid cursor"},
+		{err: errors.New("open /var/lib/feeds: permission denied"), code: types.ErrInternal, message: "Internal server error"},
+	}
+	for _, test := range tests {
+		if code, message := classify(test.err); code != test.code || message != test.message {
+			t.Errorf("classify(%v) = %s %q, want %s %q", test.err, code, message, test.code, test.message)
+		}
+	}
+}
+
+func TestComplexityCountsSources(t *testing.T) {
+	sources := make([]string, maxSources)
+	for i := range sources {
+		sources[i] = fmt.Sprintf("%q", fmt.Sprintf("https://example.com/%d.ics", i))
+	}
+
+	// Ten calendars of ten sources each would parse a hundred feeds
+	var fields []string
+	for i := 0; i < 10; i++ {
+		fields = append(fields, fmt.Sprintf(`c%d: calendar(sources: [%s]) { freeBusy { startTime } }`, i, strings.Join(sources, ",")))
+	}
+
+	resp := query(t, "{ "+strings.Join(fields, " ")+" }")
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "complexity") {
+		t.Errorf("aliased calendars over the complexity limit were not rejected: %+v", resp.Errors)
+	}
+}
+
+func TestDistinctSourcesLimit(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	var fields []string
+	for i := 0; i <= maxSources; i++ {
+		fields = append(fields, fmt.Sprintf(`c%d: calendar(sources: ["%s/%d.ics"]) { freeBusy { startTime } }`, i, closed.URL, i))
+	}
+
+	resp := query(t, "{ "+strings.Join(fields, " ")+" }")
+	var limited int
+	for _, err := range resp.Errors {
+		if strings.Contains(err.Message, "at most 10 distinct sources") {
+			limited++
+		}
+	}
+	if limited != 1 {
+		t.Errorf("%d calendars were refused for naming too many sources, want 1: %+v", limited, resp.Errors)
+	}
+}
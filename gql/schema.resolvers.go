@@ -0,0 +1,169 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.43
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/redact"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+// NextEvent is the resolver for the nextEvent field.
+func (r *calendarResolver) NextEvent(ctx context.Context, obj *Calendar, showInProgress *bool, filter *string, width *int, maxLines *int) (*types.NextEventResponse, error) {
+	req := obj.request(filter)
+	if showInProgress != nil {
+		req.ShowInProgress = *showInProgress
+	}
+	if width != nil {
+		req.Width = *width
+	}
+	if maxLines != nil {
+		req.MaxLines = *maxLines
+	}
+
+	events, err := loaderFor(ctx).events(obj)
+	if err != nil {
+		return nil, err
+	}
+	events, filteredOut, err := r.ICS.Filter(events, req)
+	if err != nil {
+		return nil, err
+	}
+
+	nextEvent := r.ICS.NextEvent(events)
+	if nextEvent != nil {
+		r.ICS.Display(nextEvent, req)
+	}
+
+	state := r.ICS.State(nextEvent, filteredOut, obj.Window, time.Now())
+	return &state, nil
+}
+
+// Events is the resolver for the events field.
+func (r *calendarResolver) Events(ctx context.Context, obj *Calendar, filter *string, first *int, after *string) (*EventConnection, error) {
+	req := obj.request(filter)
+	req.ShowInProgress = true
+
+	offset := 0
+	if after != nil {
+		var err error
+		if offset, err = decodeCursor(*after); err != nil {
+			return nil, err
+		}
+	}
+
+	events, err := loaderFor(ctx).events(obj)
+	if err != nil {
+		return nil, err
+	}
+	events, filteredOut, err := r.ICS.Filter(events, req)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StartTime < events[j].StartTime
+	})
+
+	end := len(events)
+	if first != nil && *first >= 0 {
+		end = min(offset+*first, len(events))
+	}
+	offset = min(offset, end)
+
+	conn := &EventConnection{
+		Edges:       []*EventEdge{},
+		PageInfo:    &PageInfo{HasNextPage: end < len(events)},
+		TotalCount:  len(events),
+		FilteredOut: filteredOut,
+	}
+
+	now := time.Now().Unix()
+	for i := offset; i < end; i++ {
+		e := events[i]
+		r.ICS.Annotate(&e, now)
+		r.ICS.Display(&e, req)
+		conn.Edges = append(conn.Edges, &EventEdge{Cursor: encodeCursor(i + 1), Node: &e})
+	}
+	if n := len(conn.Edges); n > 0 {
+		conn.PageInfo.EndCursor = &conn.Edges[n-1].Cursor
+	}
+
+	return conn, nil
+}
+
+// FreeBusy is the resolver for the freeBusy field.
+func (r *calendarResolver) FreeBusy(ctx context.Context, obj *Calendar) ([]*types.BusyPeriod, error) {
+	events, err := loaderFor(ctx).events(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := []*types.BusyPeriod{}
+	for _, period := range r.ICS.FreeBusy(events) {
+		period := period
+		busy = append(busy, &period)
+	}
+	return busy, nil
+}
+
+// Lines is the resolver for the lines field.
+func (r *eventResolver) Lines(ctx context.Context, obj *types.Event) ([]*FontLines, error) {
+	lines := []*FontLines{}
+	for font, l := range obj.Lines {
+		lines = append(lines, &FontLines{Font: font, Lines: l})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].Font < lines[j].Font
+	})
+	return lines, nil
+}
+
+// State is the resolver for the state field.
+func (r *nextEventResolver) State(ctx context.Context, obj *types.NextEventResponse) (string, error) {
+	return string(obj.State), nil
+}
+
+// Calendar is the resolver for the calendar field.
+func (r *queryResolver) Calendar(ctx context.Context, sources []string, tz *string, window *int) (*Calendar, error) {
+	if len(sources) == 0 {
+		return nil, errNoSources
+	}
+	if err := checkArguments(sources, tz, window); err != nil {
+		return nil, err
+	}
+
+	r.Logger.Info("Calendar", zap.Strings("sources", redact.URLs(sources)))
+
+	cal := &Calendar{Sources: sources}
+	if tz != nil {
+		cal.TZ = *tz
+	}
+	if window != nil {
+		cal.Window = *window
+	}
+	return cal, nil
+}
+
+// Calendar returns CalendarResolver implementation.
+func (r *Resolver) Calendar() CalendarResolver { return &calendarResolver{r} }
+
+// Event returns EventResolver implementation.
+func (r *Resolver) Event() EventResolver { return &eventResolver{r} }
+
+// NextEvent returns NextEventResolver implementation.
+func (r *Resolver) NextEvent() NextEventResolver { return &nextEventResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type calendarResolver struct{ *Resolver }
+type eventResolver struct{ *Resolver }
+type nextEventResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
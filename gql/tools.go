@@ -0,0 +1,6 @@
+//go:build tools
+// +build tools
+
+package gql
+
+import _ "github.com/99designs/gqlgen"
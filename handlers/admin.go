@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/keyauth"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// AdminAuth guards the admin routes with a static bearer token
+func (h Handlers) AdminAuth(token string) fiber.Handler {
+	return keyauth.New(keyauth.Config{
+		Validator: func(c *fiber.Ctx, key string) (bool, error) {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(token)) != 1 {
+				return false, keyauth.ErrMissingOrMalformedAPIKey
+			}
+			return true, nil
+		},
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return &APIError{Code: t.ErrUnauthorized, Message: "A valid admin token is required", Err: err}
+		},
+	})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/keyauth"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/apikey"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+// APIKeyHeader carries the API key of a request
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuth authenticates requests that send an API key and puts the key's
+// claims in the request's user context. Requests without one, or already
+// authenticated with a client certificate, are passed on.
+func (h Handlers) APIKeyAuth(keys *apikey.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		secret := c.Get(APIKeyHeader)
+		if _, ok := auth.FromContext(c.UserContext()); ok || secret == "" {
+			return c.Next()
+		}
+
+		key, err := keys.Authenticate(secret)
+		if err != nil {
+			return &APIError{Code: t.ErrUnauthorized, Message: "The API key is invalid or expired", Err: err}
+		}
+		c.SetUserContext(auth.NewContext(c.UserContext(), key.Claims()))
+		return c.Next()
+	}
+}
+
+// ClientCertAuth authenticates requests made with a verified client
+// certificate and puts the claims of its identity in the request's user
+// context. Requests without one are passed on.
+func (h Handlers) ClientCertAuth(identities []auth.CertIdentity) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		state := c.Context().TLSConnectionState()
+		if state == nil || len(state.VerifiedChains) == 0 {
+			return c.Next()
+		}
+
+		claims := auth.CertClaims(state.VerifiedChains[0][0], identities)
+		c.SetUserContext(auth.NewContext(c.UserContext(), claims))
+		return c.Next()
+	}
+}
+
+// Authenticate checks the bearer token of a request and puts its claims in
+// the request's user context. Requests already authenticated with a client
+// certificate or an API key are passed on.
+func (h Handlers) Authenticate(verifier *auth.Verifier) fiber.Handler {
+	return keyauth.New(keyauth.Config{
+		Next: func(c *fiber.Ctx) bool {
+			_, ok := auth.FromContext(c.UserContext())
+			return ok
+		},
+		Validator: func(c *fiber.Ctx, token string) (bool, error) {
+			claims, err := verifier.Verify(token)
+			if err != nil {
+				h.Logger.Debug("Authenticate", zap.String("requestId", requestID(c)), zap.Error(err))
+				return false, err
+			}
+			c.SetUserContext(auth.NewContext(c.UserContext(), claims))
+			return true, nil
+		},
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return &APIError{Code: t.ErrUnauthorized, Message: "A valid bearer token is required", Err: err}
+		},
+	})
+}
+
+// RequireScopes rejects requests whose token does not grant every one of
+// scopes, it must run after Authenticate
+func (h Handlers) RequireScopes(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := auth.FromContext(c.UserContext())
+		if !ok {
+			return NewError(t.ErrUnauthorized, "A bearer token or API key is required")
+		}
+		if !claims.HasScopes(scopes...) {
+			return NewError(t.ErrForbidden, "The credentials lack the scopes "+strings.Join(scopes, ", "))
+		}
+		return c.Next()
+	}
+}
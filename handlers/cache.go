@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+)
+
+// sendCached writes body with a strong ETag derived from key and lets clients
+// cache it for maxAge seconds, the time until the display next changes. The
+// body may differ on every request, by its request ID and countdowns, so key
+// covers every field the client displays instead. Clients that already
+// display the same thing get a 304. Responses to authenticated requests are private, so
+// shared caches never hand them to clients that did not authenticate.
+func sendCached(c *fiber.Ctx, body interface{}, key string, maxAge int64) error {
+	sum := sha256.Sum256([]byte(key))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	scope := "public"
+	if _, ok := auth.FromContext(c.UserContext()); ok {
+		scope = "private"
+		c.Vary(fiber.HeaderAuthorization, APIKeyHeader)
+	}
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("%s, max-age=%d", scope, max(maxAge, 0)))
+
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.JSON(body)
+}
+
+// etagMatches applies the weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+)
+
+func TestSendCachedETagFollowsKey(t *testing.T) {
+	// The countdown differs on every request, only the key should matter
+	countdown := 0
+	key := "upcoming|standup"
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		countdown++
+		return sendCached(c, map[string]int{"secondsUntilStart": countdown}, key, 60)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("ETag %q is not strong", etag)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, etag)
+	if resp, err = app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("got status %d for an unchanged display, want 304", resp.StatusCode)
+	}
+
+	// Clients may send the tag back weak
+	req.Header.Set(fiber.HeaderIfNoneMatch, "W/"+etag)
+	if resp, err = app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("got status %d for a weak If-None-Match, want 304", resp.StatusCode)
+	}
+
+	key = "in-progress|standup"
+	if resp, err = app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderETag) == etag {
+		t.Errorf("got status %d and ETag %q after the display changed", resp.StatusCode, resp.Header.Get(fiber.HeaderETag))
+	}
+}
+
+func TestSendCachedPrivateWhenAuthenticated(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return sendCached(c, "standup", "upcoming|standup", 60)
+	})
+	app.Get("/authenticated", func(c *fiber.Ctx) error {
+		c.SetUserContext(auth.NewContext(c.UserContext(), auth.Claims{}))
+		return sendCached(c, "standup", "upcoming|standup", 60)
+	})
+
+	tests := []struct {
+		path, cacheControl, vary string
+	}{
+		{path: "/", cacheControl: "public, max-age=60", vary: ""},
+		{path: "/authenticated", cacheControl: "private, max-age=60", vary: "Authorization, X-API-Key"},
+	}
+	for _, test := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", test.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get(fiber.HeaderCacheControl); got != test.cacheControl {
+			t.Errorf("%s: Cache-Control = %q, want %q", test.path, got, test.cacheControl)
+		}
+		if got := resp.Header.Get(fiber.HeaderVary); got != test.vary {
+			t.Errorf("%s: Vary = %q, want %q", test.path, got, test.vary)
+		}
+	}
+}
@@ -1,40 +1,78 @@
 package handlers
 
 import (
+	"fmt"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/redact"
 	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
 	"go.uber.org/zap"
 )
 
 func (h Handlers) NextEventHandler(c *fiber.Ctx) error {
-	var icsRequest t.IcsRequest
+	icsRequest := newRequest()
 
 	if err := c.BodyParser(&icsRequest); err != nil {
-		return c.Status(400).SendString(err.Error())
+		return &APIError{Code: t.ErrBadRequest, Message: "Invalid request body", Err: err}
+	}
+	if err := validate(icsRequest); err != nil {
+		return err
 	}
 
-	h.Logger.Info("NextEventHandler", zap.String("url", icsRequest.ICSUrl))
+	nextEvent, err := h.nextEvent(icsRequest)
+	if err != nil {
+		return err
+	}
 
-	calString, err := h.Calendar.DownloadCalendar(icsRequest.ICSUrl)
+	body, err := present(c, nextEvent)
 	if err != nil {
-		return c.Status(400).SendString(err.Error())
+		return err
 	}
+	return c.JSON(body)
+}
+
+// NextEventQueryHandler is the cacheable GET variant of NextEventHandler that
+// reads its parameters from the query string
+func (h Handlers) NextEventQueryHandler(c *fiber.Ctx) error {
+	icsRequest := newRequest()
 
-	h.Logger.Info("NextEventHandler", zap.String("calString", calString))
+	if err := c.QueryParser(&icsRequest); err != nil {
+		return &APIError{Code: t.ErrBadRequest, Message: "Invalid query parameters", Err: err}
+	}
+	if err := validate(icsRequest); err != nil {
+		return err
+	}
 
-	events, err := h.Calendar.ParseCalendar(calString, icsRequest.TZ)
+	nextEvent, err := h.nextEvent(icsRequest)
 	if err != nil {
-		return c.Status(400).SendString(err.Error())
+		return err
 	}
 
-	h.Logger.Info("NextEventHandler", zap.Any("events", events))
+	body, err := present(c, nextEvent)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("v%d|%s", apiVersion(c), calendar.DisplayKey(nextEvent))
+	return sendCached(c, body, key, nextEvent.RefreshInSeconds)
+}
 
-	nextEvent := h.Calendar.NextEvent(events)
-	if nextEvent == nil {
-		return c.Status(404).SendString("No upcoming events")
+// newRequest returns the request parameters are parsed into. Events in
+// progress are kept unless the client sends showInProgress=false, as they
+// were before the parameter was read.
+func newRequest() t.IcsRequest {
+	return t.IcsRequest{ShowInProgress: true}
+}
+
+func (h Handlers) nextEvent(icsRequest t.IcsRequest) (t.NextEventResponse, error) {
+	h.Logger.Info("NextEventHandler", zap.String("url", redact.URL(icsRequest.ICSUrl)))
+
+	nextEvent, err := h.Calendar.Next(icsRequest)
+	if err != nil {
+		return t.NextEventResponse{}, err
 	}
 
-	h.Logger.Info("NextEventHandler", zap.Any("nextEvent", nextEvent))
+	h.Logger.Debug("NextEventHandler", zap.Any("nextEvent", nextEvent))
 
-	return c.JSON(nextEvent)
+	return nextEvent, nil
 }
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"go.uber.org/zap"
+)
+
+const icsTime = "20060102T150405Z"
+
+// progressFeed serves a feed with a meeting in progress and one later on
+func progressFeed(t *testing.T) string {
+	now := time.Now().UTC().Truncate(time.Minute)
+	event := func(name string, start time.Time) string {
+		return fmt.Sprintf("BEGIN:VEVENT\r\nUID:%s\r\nDTSTAMP:%s\r\nSUMMARY:%s\r\nDTSTART:%s\r\nDTEND:%s\r\nEND:VEVENT\r\n",
+			name, now.Format(icsTime), name, start.Format(icsTime), start.Add(time.Hour).Format(icsTime))
+	}
+	feed := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" +
+		event("Ongoing", now.Add(-10*time.Minute)) +
+		event("Later", now.Add(2*time.Hour)) +
+		"END:VCALENDAR\r\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		io.WriteString(w, feed)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/cal.ics"
+}
+
+func TestNextEventKeepsInProgressByDefault(t *testing.T) {
+	feedURL := progressFeed(t)
+	logger := zap.NewNop()
+	handlers := Handlers{Logger: logger, Calendar: &calendar.Calendar{Logger: logger}}
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	app.Get("/ics/next-event", handlers.NextEventQueryHandler)
+	app.Post("/ics/next-event", handlers.NextEventHandler)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{
+			name: "query default",
+			req:  httptest.NewRequest("GET", "/ics/next-event?icsUrl="+url.QueryEscape(feedURL), nil),
+			want: "Ongoing",
+		},
+		{
+			name: "query opt out",
+			req:  httptest.NewRequest("GET", "/ics/next-event?showInProgress=false&icsUrl="+url.QueryEscape(feedURL), nil),
+			want: "Later",
+		},
+		{
+			name: "body default",
+			req:  jsonRequest(fmt.Sprintf(`{"icsUrl":%q}`, feedURL)),
+			want: "Ongoing",
+		},
+		{
+			name: "body opt out",
+			req:  jsonRequest(fmt.Sprintf(`{"icsUrl":%q,"showInProgress":false}`, feedURL)),
+			want: "Later",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := app.Test(test.req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status %d: %s", resp.StatusCode, body)
+			}
+			if !strings.Contains(string(body), `"Name":"`+test.want+`"`) {
+				t.Errorf("next event is not %s: %s", test.want, body)
+			}
+		})
+	}
+}
+
+func TestNextEventVersions(t *testing.T) {
+	// The envelope and idle state are v2 only, v1 and the unversioned routes
+	// keep the bare event and 404. Errors are in the envelope everywhere.
+	// name:Lunch filters out every meeting.
+	feedURL := url.QueryEscape(progressFeed(t))
+	logger := zap.NewNop()
+	handlers := Handlers{Logger: logger, Calendar: &calendar.Calendar{Logger: logger}}
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	app.Use(requestid.New())
+	app.Get("/ics/next-event", handlers.NextEventQueryHandler)
+	app.Group("/v1", handlers.Version(1)).Get("/ics/next-event", handlers.NextEventQueryHandler)
+	app.Group("/v2", handlers.Version(2)).Get("/ics/next-event", handlers.NextEventQueryHandler)
+
+	type result struct {
+		status int
+		// body is what the body starts with
+		body string
+	}
+	enveloped := func(status int, code string) result {
+		return result{status, `{"data":null,"message":"","error":{"code":"` + code + `"`}
+	}
+	tests := []struct {
+		name  string
+		query string
+		// want is the result of each route prefix
+		want map[string]result
+	}{
+		{
+			name:  "upcoming",
+			query: "filter=name:Later&icsUrl=" + feedURL,
+			want: map[string]result{
+				"":    {fiber.StatusOK, `{"Name":"Later","StartTime":`},
+				"/v1": {fiber.StatusOK, `{"Name":"Later","StartTime":`},
+				"/v2": {fiber.StatusOK, `{"data":{"state":"upcoming","event":{"id":`},
+			},
+		},
+		{
+			name:  "idle",
+			query: "filter=name:Lunch&icsUrl=" + feedURL,
+			want: map[string]result{
+				"":    enveloped(fiber.StatusNotFound, "NO_EVENTS"),
+				"/v1": enveloped(fiber.StatusNotFound, "NO_EVENTS"),
+				"/v2": {fiber.StatusOK, `{"data":{"state":"idle","event":null`},
+			},
+		},
+		{
+			name:  "invalid",
+			query: "icsUrl=ftp://example.com/cal.ics",
+			want: map[string]result{
+				"":    enveloped(fiber.StatusBadRequest, "VALIDATION_FAILED"),
+				"/v1": enveloped(fiber.StatusBadRequest, "VALIDATION_FAILED"),
+				"/v2": enveloped(fiber.StatusBadRequest, "VALIDATION_FAILED"),
+			},
+		},
+	}
+	for _, test := range tests {
+		for prefix, want := range test.want {
+			t.Run(test.name+prefix, func(t *testing.T) {
+				resp, err := app.Test(httptest.NewRequest("GET", prefix+"/ics/next-event?"+test.query, nil), -1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+
+				if resp.StatusCode != want.status {
+					t.Errorf("status = %d, want %d", resp.StatusCode, want.status)
+				}
+				if !strings.HasPrefix(string(body), want.body) {
+					t.Errorf("body %s does not start with %s", body, want.body)
+				}
+				if strings.HasPrefix(want.body, `{"data":`) && !strings.Contains(string(body), `"requestId":"`) {
+					t.Errorf("envelope %s has no request id", body)
+				}
+			})
+		}
+	}
+}
+
+func jsonRequest(body string) *http.Request {
+	req := httptest.NewRequest("POST", "/ics/next-event", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return req
+}
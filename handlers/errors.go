@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+// statuses maps every error code to the HTTP status it is reported with
+var statuses = map[t.ErrorCode]int{
+	t.ErrBadRequest:       fiber.StatusBadRequest,
+	t.ErrValidation:       fiber.StatusBadRequest,
+	t.ErrUnauthorized:     fiber.StatusUnauthorized,
+	t.ErrForbidden:        fiber.StatusForbidden,
+	t.ErrNotFound:         fiber.StatusNotFound,
+	t.ErrMethodNotAllowed: fiber.StatusMethodNotAllowed,
+	t.ErrFeedUnreachable:  fiber.StatusBadGateway,
+	t.ErrFeedNotICS:       fiber.StatusUnprocessableEntity,
+	t.ErrBadTZ:            fiber.StatusBadRequest,
+	t.ErrRateLimited:      fiber.StatusTooManyRequests,
+	t.ErrNoEvents:         fiber.StatusNotFound,
+	t.ErrInternal:         fiber.StatusInternalServerError,
+}
+
+// APIError is an error that is reported to clients in the response envelope.
+// Message and Fields are shown to clients, Err is only logged.
+type APIError struct {
+	Code    t.ErrorCode
+	Message string
+	Fields  []t.FieldError
+	Err     error
+}
+
+// NewError creates an APIError with a client facing message
+func NewError(code t.ErrorCode, message string) *APIError {
+	return &APIError{Code: code, Message: message}
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status the error is reported with
+func (e *APIError) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return fiber.StatusInternalServerError
+}
+
+// ErrorHandler is the fiber error handler, it writes every error in the
+// response envelope without leaking upstream error details
+func (h Handlers) ErrorHandler(c *fiber.Ctx, err error) error {
+	apiErr := toAPIError(err)
+
+	if apiErr.Status() >= fiber.StatusInternalServerError {
+		h.Logger.Error("ErrorHandler", zap.String("requestId", requestID(c)), zap.Error(err))
+	} else {
+		h.Logger.Info("ErrorHandler", zap.String("requestId", requestID(c)), zap.Error(err))
+	}
+
+	return c.Status(apiErr.Status()).JSON(t.BaseResponse[any]{
+		Error:     &t.ErrorBody{Code: apiErr.Code, Message: apiErr.Message, Fields: apiErr.Fields},
+		RequestID: requestID(c),
+	})
+}
+
+// toAPIError classifies an error into the error code catalog
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		switch fiberErr.Code {
+		case fiber.StatusNotFound:
+			return &APIError{Code: t.ErrNotFound, Message: "Route not found", Err: err}
+		case fiber.StatusMethodNotAllowed:
+			return &APIError{Code: t.ErrMethodNotAllowed, Message: "Method not allowed", Err: err}
+		case fiber.StatusTooManyRequests:
+			return &APIError{Code: t.ErrRateLimited, Message: "Too many requests", Err: err}
+		}
+		if fiberErr.Code < fiber.StatusInternalServerError {
+			return &APIError{Code: t.ErrBadRequest, Message: fiberErr.Message, Err: err}
+		}
+	}
+
+	switch {
+	case errors.Is(err, calendar.ErrFeedUnreachable):
+		return &APIError{Code: t.ErrFeedUnreachable, Message: "The calendar feed could not be downloaded", Err: err}
+	case errors.Is(err, calendar.ErrFeedNotICS):
+		return &APIError{Code: t.ErrFeedNotICS, Message: "The calendar feed is not a valid ICS file", Err: err}
+	case errors.Is(err, calendar.ErrBadFilter):
+		return &APIError{Code: t.ErrValidation, Message: "The filter is invalid", Err: err}
+	case errors.Is(err, calendar.ErrBadTZ):
+		return &APIError{Code: t.ErrBadTZ, Message: "Unknown time zone, use an IANA name such as America/Chicago", Err: err}
+	}
+
+	return &APIError{Code: t.ErrInternal, Message: "Internal server error", Err: err}
+}
+
+// requestID returns the id the requestid middleware assigned to the request
+func requestID(c *fiber.Ctx) string {
+	return c.GetRespHeader(fiber.HeaderXRequestID)
+}
+
+// LimitReachedHandler reports a request rejected by the rate limiter
+func (h Handlers) LimitReachedHandler(c *fiber.Ctx) error {
+	return NewError(t.ErrRateLimited, "Too many requests")
+}
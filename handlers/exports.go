@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/export"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// exportMaxAge is how long subscribers may cache an exported calendar
+const exportMaxAge = 5 * 60
+
+// CreateExportHandler adds an export feed and returns the token it is served under
+func (h Handlers) CreateExportHandler(c *fiber.Ctx) error {
+	var feed export.Feed
+
+	if err := c.BodyParser(&feed); err != nil {
+		return &APIError{Code: t.ErrBadRequest, Message: "Invalid request body", Err: err}
+	}
+	if err := validateExport(feed); err != nil {
+		return err
+	}
+
+	feed, token, err := h.Exports.Add(feed)
+	if err != nil {
+		return err
+	}
+
+	c.Status(fiber.StatusCreated)
+	return respond(c, export.Created{Feed: feed.Redacted(), Token: token, Path: "/ics/export/" + token + ".ics"})
+}
+
+// ListExportsHandler returns every export feed, tokens can not be recovered
+func (h Handlers) ListExportsHandler(c *fiber.Ctx) error {
+	feeds := h.Exports.Feeds()
+	for i := range feeds {
+		feeds[i] = feeds[i].Redacted()
+	}
+	return respond(c, feeds)
+}
+
+// DeleteExportHandler removes an export feed
+func (h Handlers) DeleteExportHandler(c *fiber.Ctx) error {
+	err := h.Exports.Remove(c.Params("id"))
+	if errors.Is(err, export.ErrNotFound) {
+		return &APIError{Code: t.ErrNotFound, Message: "Export not found", Err: err}
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ExportHandler serves an export feed as an ICS calendar
+func (h Handlers) ExportHandler(c *fiber.Ctx) error {
+	feed, err := h.Exports.ByToken(c.Params("token"))
+	if err != nil {
+		return &APIError{Code: t.ErrNotFound, Message: "Export not found", Err: err}
+	}
+
+	events, err := export.Events(h.Calendar, feed)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if err := export.Write(&body, feed, events, time.Now()); err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", exportMaxAge))
+	return c.Send(body.Bytes())
+}
+
+// validateExport checks an export feed against its schema and its sources
+func validateExport(feed export.Feed) error {
+	var fields []t.FieldError
+	if err := validate(feed); err != nil {
+		fields = err.(*APIError).Fields
+	}
+
+	for i, source := range feed.Sources {
+		if err := calendar.CheckFeedURL(source); err != nil {
+			fields = append(fields, t.FieldError{Field: fmt.Sprintf("sources[%d]", i), Message: err.Error()})
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return &APIError{Code: t.ErrValidation, Message: "The request is invalid", Fields: fields}
+}
@@ -2,10 +2,14 @@ package handlers
 
 import (
 	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/export"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/webhook"
 	"go.uber.org/zap"
 )
 
 type Handlers struct {
 	Logger   *zap.Logger
 	Calendar *c.Calendar
+	Webhooks *webhook.Store
+	Exports  *export.Store
 }
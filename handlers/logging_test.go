@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/redact"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const (
+	feedToken     = "q7Zk2Lw9Rt4Xy8Pm3Nv6"
+	privateKey    = "private-0f9e8d7c6b5a4f3e"
+	bodyMarker    = "Body marker 51c3"
+	tokenFeedPath = "/calendar/ical/ann%40example.com/" + privateKey + "/basic.ics"
+)
+
+// TestNextEventKeepsSecretsOutOfLogs sends a feed URL with a query token and a
+// Google private segment through the handler, at debug level, and looks for the
+// secrets and the feed body in every entry and field
+func TestNextEventKeepsSecretsOutOfLogs(t *testing.T) {
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Minute)
+	feed := fmt.Sprintf("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n"+
+		"BEGIN:VEVENT\r\nUID:standup\r\nDTSTAMP:%s\r\nSUMMARY:Standup\r\nDESCRIPTION:%s\r\nDTSTART:%s\r\nDTEND:%s\r\nEND:VEVENT\r\n"+
+		"END:VCALENDAR\r\n", start.Format(icsTime), bodyMarker, start.Format(icsTime), start.Add(30*time.Minute).Format(icsTime))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		io.WriteString(w, feed)
+	}))
+	defer server.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	observed, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(redact.WrapCore(observed))
+	handlers := Handlers{Logger: logger, Calendar: &calendar.Calendar{Logger: logger}}
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	app.Post("/ics/next-event", handlers.NextEventHandler)
+
+	for _, base := range []string{server.URL, unreachable.URL} {
+		feedURL := base + tokenFeedPath + "?token=" + feedToken
+		resp, err := app.Test(jsonRequest(fmt.Sprintf(`{"icsUrl":%q}`, feedURL)), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if logs.Len() == 0 {
+		t.Fatal("nothing was logged")
+	}
+	for _, entry := range logs.All() {
+		text := entry.Message + " " + fmt.Sprint(entry.ContextMap())
+		for _, secret := range []string{feedToken, privateKey, bodyMarker} {
+			if strings.Contains(text, secret) {
+				t.Errorf("%q logged in %s", secret, text)
+			}
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	apiv1 "github.com/quesurifn/ics-calendar-tidbyt-server/api/v1"
+	apiv2 "github.com/quesurifn/ics-calendar-tidbyt-server/api/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/export"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/openapi"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/webhook"
+)
+
+// apiDocument is built once at start up, which also registers every schema
+// the validator needs before requests are served concurrently
+var apiDocument = buildDocument()
+
+func buildDocument() *openapi.Document {
+	doc := openapi.NewDocument(openapi.Info{
+		Title: "Tidbyt ICS Server",
+		Description: "Turns ICS calendar feeds into display ready data for Tidbyt apps. " +
+			"The next-event operations under /v2 answer with the display state in the response envelope, idle when there is no next event. " +
+			"The unversioned and /v1 operations keep the original contract for deployed apps: the bare next event, and 404 when there is none. " +
+			"Errors are reported in the envelope by every operation.",
+		Version: "1.0.0",
+	}, schemas)
+
+	request := reflect.TypeOf(t.IcsRequest{})
+	responses := map[string]openapi.Response{
+		"400": errorResponse("The request is invalid"),
+		"401": errorResponse("The bearer token or API key is missing or invalid, when authentication is configured"),
+		"403": errorResponse("The credentials lack the calendar:read scope"),
+		"422": errorResponse("The feed is not an ICS calendar"),
+		"429": errorResponse("Too many requests, Retry-After is how many seconds to wait"),
+		"502": errorResponse("The feed could not be downloaded"),
+	}
+
+	// The next-event operations are served under /v1 and /v2, the unversioned
+	// paths are aliases of v1. v1 answers with the bare next event and 404 when
+	// there is none, v2 with the display state in the envelope.
+	versions := []struct {
+		prefix, id, note string
+		response         reflect.Type
+		description      string
+	}{
+		{"", "", " Alias of the /v1 operation.", reflect.TypeOf(apiv1.Event{}), "The next event"},
+		{"/v1", "V1", "", reflect.TypeOf(apiv1.Event{}), "The next event"},
+		{"/v2", "V2", "", reflect.TypeOf(t.BaseResponse[apiv2.NextEventResponse]{}), "The display state"},
+	}
+	for _, v := range versions {
+		withState := map[string]openapi.Response{"200": {Description: v.description, Content: schemas.JSON(v.response)}}
+		for status, response := range responses {
+			withState[status] = response
+		}
+		if v.id != "V2" {
+			withState["404"] = errorResponse("There is no next event in the lookahead window")
+		}
+
+		doc.Add(v.prefix+"/ics/next-event", "get", &openapi.Operation{
+			Summary:     "Next event of a feed",
+			Description: "Cacheable variant of the POST operation. Theme rules use dotted names, e.g. theme.0.color." + v.note,
+			OperationID: "getNextEvent" + v.id,
+			Tags:        []string{"calendar"},
+			Parameters:  schemas.QueryParameters(request),
+			Responses:   withNotModified(withState),
+		})
+		doc.Add(v.prefix+"/ics/next-event", "post", &openapi.Operation{
+			Summary:     "Next event of a feed",
+			Description: strings.TrimSpace(v.note),
+			OperationID: "postNextEvent" + v.id,
+			Tags:        []string{"calendar"},
+			RequestBody: &openapi.RequestBody{Required: true, Content: schemas.JSON(request)},
+			Responses:   withState,
+		})
+		doc.Add(v.prefix+"/ics/next-event/stream", "get", &openapi.Operation{
+			Summary: "Stream the next event of a feed",
+			Description: "Server-Sent Events stream of next-event events, each carrying the same body as the GET operation, null when v1 has no next event. " +
+				"An event is sent on connect and whenever the display changes. Send Last-Event-ID to skip a state the client already holds." + v.note,
+			OperationID: "streamNextEvent" + v.id,
+			Tags:        []string{"calendar"},
+			Parameters:  schemas.QueryParameters(request),
+			Responses: map[string]openapi.Response{
+				"200": {Description: "A text/event-stream of display states", Content: map[string]openapi.MediaType{
+					"text/event-stream": {Schema: &openapi.Schema{Type: "string"}},
+				}},
+				"400": responses["400"],
+				"401": responses["401"],
+				"403": responses["403"],
+				"422": responses["422"],
+				"429": errorResponse("Too many requests or open streams"),
+				"502": responses["502"],
+			},
+		})
+	}
+
+	subscription := reflect.TypeOf(webhook.Subscription{})
+	unauthorized := errorResponse("The admin token, or the bearer token or API key when authentication is configured, is missing or wrong")
+	forbidden := errorResponse("The credentials lack the admin scope")
+	doc.Add("/admin/webhooks", "get", &openapi.Operation{
+		Summary:     "List webhook subscriptions",
+		Description: "Requires admin access. Secrets are never returned.",
+		OperationID: "listWebhooks",
+		Tags:        []string{"admin"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Every subscription", Content: schemas.JSON(reflect.TypeOf(t.BaseResponse[[]webhook.Subscription]{}))},
+			"401": unauthorized,
+			"403": forbidden,
+		},
+	})
+	doc.Add("/admin/webhooks", "post", &openapi.Operation{
+		Summary: "Subscribe to transitions of a feed",
+		Description: "Requires admin access. Transitions are posted as JSON signed in the " + webhook.SignatureHeader +
+			" header as t=<unix time>,v1=<hex HMAC-SHA256 of the time, a dot and the body>.",
+		OperationID: "createWebhook",
+		Tags:        []string{"admin"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: schemas.JSON(subscription)},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "The subscription", Content: schemas.JSON(reflect.TypeOf(t.BaseResponse[webhook.Subscription]{}))},
+			"400": responses["400"],
+			"401": unauthorized,
+			"403": forbidden,
+		},
+	})
+	doc.Add("/admin/webhooks/{id}", "delete", &openapi.Operation{
+		Summary:     "Remove a webhook subscription",
+		OperationID: "deleteWebhook",
+		Tags:        []string{"admin"},
+		Parameters:  []openapi.Parameter{{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The subscription was removed"},
+			"401": unauthorized,
+			"403": forbidden,
+			"404": errorResponse("No subscription has the id"),
+		},
+	})
+	doc.Add("/admin/webhooks/deliveries", "get", &openapi.Operation{
+		Summary:     "Webhook delivery log",
+		Description: "Newest deliveries first.",
+		OperationID: "listWebhookDeliveries",
+		Tags:        []string{"admin"},
+		Parameters: []openapi.Parameter{
+			{Name: "subscriptionId", In: "query", Description: "Only deliveries of this subscription", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "limit", In: "query", Description: "How many deliveries to return", Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The deliveries", Content: schemas.JSON(reflect.TypeOf(t.BaseResponse[[]webhook.Delivery]{}))},
+			"400": responses["400"],
+			"401": unauthorized,
+			"403": forbidden,
+		},
+	})
+
+	feed := reflect.TypeOf(export.Feed{})
+	doc.Add("/ics/export/{token}.ics", "get", &openapi.Operation{
+		Summary:     "Exported calendar",
+		Description: "The merged and filtered events of an export feed as an RFC 5545 calendar.",
+		OperationID: "getExport",
+		Tags:        []string{"calendar"},
+		Parameters:  []openapi.Parameter{{Name: "token", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The calendar", Content: map[string]openapi.MediaType{
+				"text/calendar": {Schema: &openapi.Schema{Type: "string"}},
+			}},
+			"404": errorResponse("No export has the token"),
+			"422": responses["422"],
+			"502": responses["502"],
+		},
+	})
+	doc.Add("/admin/exports", "get", &openapi.Operation{
+		Summary:     "List export feeds",
+		Description: "Requires admin access. Tokens are only shown when a feed is created.",
+		OperationID: "listExports",
+		Tags:        []string{"admin"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Every export feed", Content: schemas.JSON(reflect.TypeOf(t.BaseResponse[[]export.Feed]{}))},
+			"401": unauthorized,
+			"403": forbidden,
+		},
+	})
+	doc.Add("/admin/exports", "post", &openapi.Operation{
+		Summary:     "Create an export feed",
+		Description: "Requires admin access.",
+		OperationID: "createExport",
+		Tags:        []string{"admin"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: schemas.JSON(feed)},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "The feed and its token", Content: schemas.JSON(reflect.TypeOf(t.BaseResponse[export.Created]{}))},
+			"400": responses["400"],
+			"401": unauthorized,
+			"403": forbidden,
+		},
+	})
+	doc.Add("/admin/exports/{id}", "delete", &openapi.Operation{
+		Summary:     "Remove an export feed",
+		OperationID: "deleteExport",
+		Tags:        []string{"admin"},
+		Parameters:  []openapi.Parameter{{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The feed was removed"},
+			"401": unauthorized,
+			"403": forbidden,
+			"404": errorResponse("No export has the id"),
+		},
+	})
+
+	return doc
+}
+
+func errorResponse(description string) openapi.Response {
+	return openapi.Response{Description: description, Content: schemas.JSON(reflect.TypeOf(t.BaseResponse[any]{}))}
+}
+
+func withNotModified(responses map[string]openapi.Response) map[string]openapi.Response {
+	withStatus := map[string]openapi.Response{"304": {Description: "The cached response is still current"}}
+	for status, response := range responses {
+		withStatus[status] = response
+	}
+	return withStatus
+}
+
+// OpenAPIHandler serves the OpenAPI document
+func (h Handlers) OpenAPIHandler(c *fiber.Ctx) error {
+	return c.JSON(apiDocument)
+}
+
+// DocsHandler serves a page that renders the OpenAPI document
+func (h Handlers) DocsHandler(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(docsPage)
+}
+
+const docsPage = `<!DOCTYPE html>
+<html>
+  <head>
+    <title>Tidbyt ICS Server API</title>
+    <meta charset="utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+  </head>
+  <body>
+    <redoc spec-url="/openapi.json"></redoc>
+    <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+  </body>
+</html>
+`
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/netutil"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ratelimit"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// clientIPKey holds the client address of a request in its locals
+const clientIPKey = "clientIP"
+
+// ClientIP resolves the address of the client behind trusted proxies, it
+// must run before anything that looks at the client address
+func (h Handlers) ClientIP(proxies netutil.Networks) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ip := proxies.ClientIP(c.Context().RemoteIP(), c.Get(fiber.HeaderXForwardedFor))
+		c.Locals(clientIPKey, ip.String())
+		return c.Next()
+	}
+}
+
+// clientIP returns the client address of a request
+func clientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals(clientIPKey).(string); ok {
+		return ip
+	}
+	return c.IP()
+}
+
+// RateLimit limits the requests of a route. Authenticated clients are counted
+// by the subject of their credentials and anonymous ones by address, so it
+// must run after authentication. Routes with their own limits are counted
+// apart from the rest.
+func (h Handlers) RateLimit(limiter *ratelimit.Limiter, path string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ip := clientIP(c)
+		if limiter.Exempt(net.ParseIP(ip)) {
+			return c.Next()
+		}
+
+		tiers, bucket := limitsOf(limiter, path)
+		key, limit := bucket+"|ip:"+ip, tiers.Anonymous
+		if claims, ok := auth.FromContext(c.UserContext()); ok && claims.Subject != "" {
+			key, limit = bucket+"|sub:"+claims.Subject, tiers.Authenticated
+		}
+
+		result := limiter.Allow(key, limit, time.Now())
+		if !result.Allowed {
+			return h.limitReached(c, limit, result)
+		}
+		setLimitHeaders(c, limit, result)
+		return c.Next()
+	}
+}
+
+// AuthFailureLimit counts the requests of a route that fail authentication
+// against the anonymous limit of the client address, and rejects addresses
+// over it before their credentials are checked again. It runs ahead of the
+// guards, which RateLimit has to follow, so guessing credentials is limited
+// like any anonymous request.
+func (h Handlers) AuthFailureLimit(limiter *ratelimit.Limiter, path string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ip := clientIP(c)
+		if limiter.Exempt(net.ParseIP(ip)) {
+			return c.Next()
+		}
+
+		tiers, bucket := limitsOf(limiter, path)
+		key, limit := bucket+"|ip:"+ip, tiers.Anonymous
+		if result := limiter.Peek(key, limit, time.Now()); !result.Allowed {
+			return h.limitReached(c, limit, result)
+		}
+
+		err := c.Next()
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (apiErr.Code == t.ErrUnauthorized || apiErr.Code == t.ErrForbidden) {
+			setLimitHeaders(c, limit, limiter.Allow(key, limit, time.Now()))
+		}
+		return err
+	}
+}
+
+// limitsOf returns the tiers of a route and the bucket its requests are
+// counted in, routes with their own limits are counted apart from the rest
+func limitsOf(limiter *ratelimit.Limiter, path string) (ratelimit.Tiers, string) {
+	tiers, own := limiter.For(path)
+	if own {
+		return tiers, path
+	}
+	return tiers, "*"
+}
+
+func setLimitHeaders(c *fiber.Ctx, limit ratelimit.Limit, result ratelimit.Result) {
+	c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Max, limit.WindowSeconds))
+	c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Set("RateLimit-Reset", resetSeconds(result))
+}
+
+func resetSeconds(result ratelimit.Result) string {
+	return strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+}
+
+// limitReached rejects a request over its limit
+func (h Handlers) limitReached(c *fiber.Ctx, limit ratelimit.Limit, result ratelimit.Result) error {
+	setLimitHeaders(c, limit, result)
+	c.Set(fiber.HeaderRetryAfter, resetSeconds(result))
+	return h.LimitReachedHandler(c)
+}
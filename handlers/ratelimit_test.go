@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ratelimit"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+func TestAuthFailureLimit(t *testing.T) {
+	limiter, err := ratelimit.New(ratelimit.Config{
+		Anonymous:     ratelimit.Limit{Max: 2, WindowSeconds: 60},
+		Authenticated: ratelimit.Limit{Max: 10, WindowSeconds: 60},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// requireKey stands in for the auth guards
+	requireKey := func(c *fiber.Ctx) error {
+		if c.Get(APIKeyHeader) != "good" {
+			return NewError(types.ErrUnauthorized, "Invalid API key")
+		}
+		claims := auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "client"}}
+		c.SetUserContext(auth.NewContext(c.UserContext(), claims))
+		return c.Next()
+	}
+	h := Handlers{Logger: zap.NewNop()}
+	app := fiber.New(fiber.Config{ErrorHandler: h.ErrorHandler})
+	app.Get("/", h.AuthFailureLimit(limiter, "/"), requireKey, h.RateLimit(limiter, "/"), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	steps := []struct {
+		key    string
+		status int
+	}{
+		// Authenticated requests are counted by subject, not by address
+		{key: "good", status: fiber.StatusOK},
+		{key: "good", status: fiber.StatusOK},
+		{key: "good", status: fiber.StatusOK},
+		// Failed attempts use up the anonymous limit of the address
+		{key: "guess", status: fiber.StatusUnauthorized},
+		{key: "guess", status: fiber.StatusUnauthorized},
+		// and the address is rejected before its credentials are checked
+		{key: "guess", status: fiber.StatusTooManyRequests},
+		{key: "good", status: fiber.StatusTooManyRequests},
+	}
+	for i, step := range steps {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(APIKeyHeader, step.key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != step.status {
+			t.Fatalf("request %d with key %q: status %d, want %d", i, step.key, resp.StatusCode, step.status)
+		}
+		if step.status == fiber.StatusTooManyRequests && resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+			t.Errorf("request %d: no Retry-After", i)
+		}
+	}
+}
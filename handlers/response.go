@@ -0,0 +1,11 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// respond writes data in the standard response envelope
+func respond[T any](c *fiber.Ctx, data T) error {
+	return c.JSON(t.BaseResponse[T]{Data: data, RequestID: requestID(c)})
+}
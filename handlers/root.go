@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"github.com/gofiber/fiber/v2"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
 	"go.uber.org/zap"
 )
 
 func (h Handlers) RootHandler(c *fiber.Ctx) error {
 	h.Logger.Info("RootHandler", zap.String("ip", c.IP()))
-	return c.SendString("Welcome to the Tidbyt ICS Server!")
+	return c.JSON(t.BaseResponse[any]{
+		Message:   "Welcome to the Tidbyt ICS Server!",
+		RequestID: requestID(c),
+	})
 }
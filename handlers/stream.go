@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxStreamsPerIP caps the open next-event streams of a single client
+	MaxStreamsPerIP = 5
+	// heartbeatInterval keeps proxies from closing idle streams and notices
+	// clients that went away
+	heartbeatInterval = 15 * time.Second
+	// streamPollInterval is how often a stream downloads the feed again when
+	// the display is not due to change sooner
+	streamPollInterval = time.Minute
+	// minStreamWait keeps a stream from spinning on an event that just ended
+	minStreamWait = time.Second
+	// streamRetry tells EventSource clients how long to wait before reconnecting
+	streamRetry = 5 * time.Second
+)
+
+// streams counts the open streams of every client IP
+var streams = &streamCounter{open: map[string]int{}}
+
+type streamCounter struct {
+	mu   sync.Mutex
+	open map[string]int
+}
+
+// acquire reserves a stream for ip, it fails once the ip holds MaxStreamsPerIP
+func (s *streamCounter) acquire(ip string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.open[ip] >= MaxStreamsPerIP {
+		return false
+	}
+	s.open[ip]++
+	return true
+}
+
+func (s *streamCounter) release(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.open[ip]--; s.open[ip] <= 0 {
+		delete(s.open, ip)
+	}
+}
+
+// NextEventStreamHandler pushes the display state as Server-Sent Events. An event
+// is sent straight away and again whenever the next event changes, a warning
+// threshold is crossed or an event starts or ends. Clients resuming with the
+// Last-Event-ID of the current state skip the initial event, event ids are
+// the calendar.DisplayKey of the state.
+func (h Handlers) NextEventStreamHandler(c *fiber.Ctx) error {
+	icsRequest := newRequest()
+
+	if err := c.QueryParser(&icsRequest); err != nil {
+		return &APIError{Code: t.ErrBadRequest, Message: "Invalid query parameters", Err: err}
+	}
+	if err := validate(icsRequest); err != nil {
+		return err
+	}
+
+	ip := clientIP(c)
+	if !streams.acquire(ip) {
+		return NewError(t.ErrRateLimited, fmt.Sprintf("At most %d streams may be open per client", MaxStreamsPerIP))
+	}
+
+	nextEvent, err := h.nextEvent(icsRequest)
+	if err != nil {
+		streams.release(ip)
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	s := &stream{
+		Handlers:  h,
+		request:   icsRequest,
+		encode:    c.App().Config().JSONEncoder,
+		version:   apiVersion(c),
+		requestID: requestID(c),
+		resumeID:  c.Get("Last-Event-ID"),
+	}
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer streams.release(ip)
+		s.run(w, nextEvent)
+	})
+
+	return nil
+}
+
+// stream is a single open next-event stream
+type stream struct {
+	Handlers
+
+	request   t.IcsRequest
+	encode    func(interface{}) ([]byte, error)
+	version   int
+	requestID string
+	resumeID  string
+}
+
+// run writes events until the client goes away
+func (s *stream) run(w *bufio.Writer, last t.NextEventResponse) {
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	if calendar.DisplayKey(last) != s.resumeID {
+		if err := s.send(w, last); err != nil {
+			return
+		}
+	} else if err := w.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	refresh := time.NewTimer(refreshWait(last))
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": heartbeat %d\n\n", time.Now().Unix())
+			if err := w.Flush(); err != nil {
+				return
+			}
+		case <-refresh.C:
+			next, err := s.Calendar.Next(s.request)
+			switch {
+			case err != nil:
+				s.Logger.Warn("NextEventStreamHandler", zap.String("requestId", s.requestID), zap.Error(err))
+			case calendar.Changed(last, next):
+				if err := s.send(w, next); err != nil {
+					return
+				}
+				last = next
+			default:
+				last = next
+			}
+			refresh.Reset(refreshWait(last))
+		}
+	}
+}
+
+// send writes the display state as a next-event event
+func (s *stream) send(w *bufio.Writer, resp t.NextEventResponse) error {
+	data, err := s.encode(body(s.version, s.requestID, resp))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "id: %s\nevent: next-event\ndata: %s\n\n", calendar.DisplayKey(resp), data)
+	return w.Flush()
+}
+
+// refreshWait is how long to wait before looking at the feed again
+func refreshWait(resp t.NextEventResponse) time.Duration {
+	wait := time.Until(time.Unix(resp.RefreshAt, 0))
+	return min(max(wait, minStreamWait), streamPollInterval)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"go.uber.org/zap"
+)
+
+// readEvent returns the fields of the next event of a stream, or nil when
+// none arrives within wait
+func readEvent(t *testing.T, lines <-chan string, wait time.Duration) map[string]string {
+	t.Helper()
+	event := map[string]string{}
+	timeout := time.After(wait)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			if line == "" {
+				if _, ok := event["data"]; ok {
+					return event
+				}
+				continue
+			}
+			if name, value, ok := strings.Cut(line, ": "); ok {
+				event[name] = value
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+func openStream(t *testing.T, streamURL, lastEventID string) <-chan string {
+	req, _ := http.NewRequest("GET", streamURL, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+func TestStreamEventIDs(t *testing.T) {
+	feedURL := progressFeed(t)
+	logger := zap.NewNop()
+	handlers := Handlers{Logger: logger, Calendar: &calendar.Calendar{Logger: logger}}
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler, DisableStartupMessage: true})
+	app.Get("/ics/next-event/stream", handlers.NextEventStreamHandler)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	// Streams only notice a closed connection on their next heartbeat
+	t.Cleanup(func() { app.ShutdownWithTimeout(100 * time.Millisecond) })
+	streamURL := "http://" + ln.Addr().String() + "/ics/next-event/stream?icsUrl=" + url.QueryEscape(feedURL)
+
+	first := readEvent(t, openStream(t, streamURL, ""), 5*time.Second)
+	if first == nil {
+		t.Fatal("no initial event")
+	}
+	request := newRequest()
+	request.ICSUrl = feedURL
+	next, err := handlers.Calendar.Next(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := calendar.DisplayKey(next); first["id"] != want {
+		t.Errorf("event id = %q, want the display key %q", first["id"], want)
+	}
+
+	// A client resuming with the current state is not sent it again
+	if event := readEvent(t, openStream(t, streamURL, first["id"]), 500*time.Millisecond); event != nil {
+		t.Errorf("resumed stream sent %v again", event)
+	}
+	if event := readEvent(t, openStream(t, streamURL, "stale"), 5*time.Second); event == nil || event["id"] != first["id"] {
+		t.Errorf("stream resumed from a stale id sent %v, want the current state", event)
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/openapi"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// schemas generates the schemas served in the OpenAPI document, requests are
+// validated against the same schemas
+var schemas = openapi.NewGenerator()
+
+var validator = calendar.NewValidator(schemas)
+
+// validate checks a request against its schema and reports every violation
+func validate(request interface{}) error {
+	violations := validator.Validate(request)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fields := make([]t.FieldError, len(violations))
+	for i, v := range violations {
+		fields[i] = t.FieldError{Field: v.Field, Message: v.Message}
+	}
+	return &APIError{Code: t.ErrValidation, Message: "The request is invalid", Fields: fields}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	apiv1 "github.com/quesurifn/ics-calendar-tidbyt-server/api/v1"
+	apiv2 "github.com/quesurifn/ics-calendar-tidbyt-server/api/v2"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// versionKey holds the API version of a request in its locals
+const versionKey = "apiVersion"
+
+// Version marks the routes of a group as serving an API version. Routes outside
+// a versioned group serve v1, they are aliases kept for deployed apps.
+func (h Handlers) Version(version int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(versionKey, version)
+		return c.Next()
+	}
+}
+
+// apiVersion returns the API version a request is served with
+func apiVersion(c *fiber.Ctx) int {
+	if version, ok := c.Locals(versionKey).(int); ok {
+		return version
+	}
+	return 1
+}
+
+// present returns the response body of the display state in the API version
+// of a request. The envelope and the idle state are v2 only: v1, and the
+// unversioned routes that alias it, answer with the bare next event and 404
+// when there is none, as they did before the API was versioned. Errors are
+// reported in the envelope in every version.
+func present(c *fiber.Ctx, resp t.NextEventResponse) (interface{}, error) {
+	version := apiVersion(c)
+	if version == 1 && resp.Event == nil {
+		return nil, NewError(t.ErrNoEvents, "No upcoming events")
+	}
+	return body(version, requestID(c), resp), nil
+}
+
+// body converts the display state into the response body of an API version,
+// v1 bodies are the bare next event and v2 bodies the state in the envelope
+func body(version int, requestID string, resp t.NextEventResponse) interface{} {
+	if version == 2 {
+		return t.BaseResponse[apiv2.NextEventResponse]{Data: apiv2.FromNextEvent(resp), RequestID: requestID}
+	}
+	return apiv1.FromNextEvent(resp)
+}
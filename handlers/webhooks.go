@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/sliceutil"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/webhook"
+)
+
+const (
+	defaultDeliveries = 100
+	maxDeliveries     = 1000
+)
+
+// CreateWebhookHandler adds a webhook subscription
+func (h Handlers) CreateWebhookHandler(c *fiber.Ctx) error {
+	var sub webhook.Subscription
+
+	if err := c.BodyParser(&sub); err != nil {
+		return &APIError{Code: t.ErrBadRequest, Message: "Invalid request body", Err: err}
+	}
+	if err := validateSubscription(sub); err != nil {
+		return err
+	}
+
+	sub, err := h.Webhooks.Add(sub)
+	if err != nil {
+		return err
+	}
+
+	c.Status(fiber.StatusCreated)
+	return respond(c, sub.Redacted())
+}
+
+// ListWebhooksHandler returns every webhook subscription without its secret
+func (h Handlers) ListWebhooksHandler(c *fiber.Ctx) error {
+	subs := h.Webhooks.Subscriptions()
+	for i := range subs {
+		subs[i] = subs[i].Redacted()
+	}
+	return respond(c, subs)
+}
+
+// DeleteWebhookHandler removes a webhook subscription
+func (h Handlers) DeleteWebhookHandler(c *fiber.Ctx) error {
+	err := h.Webhooks.Remove(c.Params("id"))
+	if errors.Is(err, webhook.ErrNotFound) {
+		return &APIError{Code: t.ErrNotFound, Message: "Webhook subscription not found", Err: err}
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// WebhookDeliveriesHandler returns the delivery log, newest first, optionally
+// narrowed to a single subscription
+func (h Handlers) WebhookDeliveriesHandler(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", defaultDeliveries)
+	if limit < 1 || limit > maxDeliveries {
+		return &APIError{Code: t.ErrValidation, Message: "The request is invalid", Fields: []t.FieldError{
+			{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxDeliveries)},
+		}}
+	}
+
+	return respond(c, h.Webhooks.Deliveries(c.Query("subscriptionId"), limit))
+}
+
+// validateSubscription checks a subscription against its schema and the
+// transitions and thresholds that exist
+func validateSubscription(sub webhook.Subscription) error {
+	var fields []t.FieldError
+	if err := validate(sub); err != nil {
+		fields = err.(*APIError).Fields
+	}
+
+	for i, kind := range sub.Transitions {
+		if !sliceutil.Contains(webhook.Kinds, kind) {
+			fields = append(fields, t.FieldError{Field: fmt.Sprintf("transitions[%d]", i), Message: "must be one of threshold, started, ended, rescheduled, cancelled"})
+		}
+	}
+	for i, threshold := range sub.Thresholds {
+		if !sliceutil.Contains(calendar.WarningOffsets, threshold) {
+			fields = append(fields, t.FieldError{Field: fmt.Sprintf("thresholds[%d]", i), Message: "must be one of 600, 300, 60"})
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return &APIError{Code: t.ErrValidation, Message: "The request is invalid", Fields: fields}
+}
@@ -0,0 +1,78 @@
+package mqtt
+
+import "fmt"
+
+// device groups the entities of a subscription in Home Assistant
+type device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// entity is the discovery config of a Home Assistant MQTT sensor or binary sensor
+type entity struct {
+	Name                string `json:"name"`
+	UniqueID            string `json:"unique_id"`
+	ObjectID            string `json:"object_id"`
+	StateTopic          string `json:"state_topic"`
+	ValueTemplate       string `json:"value_template"`
+	AvailabilityTopic   string `json:"availability_topic"`
+	JSONAttributesTopic string `json:"json_attributes_topic,omitempty"`
+	JSONAttributes      string `json:"json_attributes_template,omitempty"`
+	DeviceClass         string `json:"device_class,omitempty"`
+	Icon                string `json:"icon,omitempty"`
+	Device              device `json:"device"`
+}
+
+// discovery returns the discovery config of a subscription's entities by topic.
+// Every entity reads the same state topic and picks its value with a template.
+func (p *Publisher) discovery(sub Subscription) map[string]entity {
+	name := sub.Name
+	if name == "" {
+		name = sub.ID
+	}
+	objectID := p.Config.TopicPrefix + "_" + sub.ID
+
+	newEntity := func(key, label, template string) entity {
+		return entity{
+			Name:              label,
+			UniqueID:          objectID + "_" + key,
+			ObjectID:          objectID + "_" + key,
+			StateTopic:        p.StateTopic(sub.ID),
+			ValueTemplate:     template,
+			AvailabilityTopic: p.AvailabilityTopic(),
+			Device: device{
+				Identifiers:  []string{objectID},
+				Name:         name,
+				Manufacturer: "Tidbyt ICS Server",
+				Model:        "ICS calendar",
+			},
+		}
+	}
+
+	nextEvent := newEntity("next_event", "Next event", "{{ value_json.event.rawName if value_json.event else 'None' }}")
+	nextEvent.JSONAttributesTopic = p.StateTopic(sub.ID)
+	nextEvent.JSONAttributes = "{{ value_json.event | tojson if value_json.event else '{}' }}"
+	nextEvent.Icon = "mdi:calendar-clock"
+
+	state := newEntity("state", "State", "{{ value_json.state }}")
+	state.Icon = "mdi:calendar-check"
+
+	start := newEntity("start", "Next event start", "{{ (value_json.event.startTime | int) | timestamp_utc ~ '+00:00' if value_json.event else None }}")
+	start.DeviceClass = "timestamp"
+
+	inProgress := newEntity("in_progress", "In meeting", "{{ 'ON' if value_json.state == 'in_progress' else 'OFF' }}")
+	inProgress.Icon = "mdi:account-clock"
+
+	topic := func(component, key string) string {
+		return fmt.Sprintf("%s/%s/%s/%s/config", p.Config.DiscoveryPrefix, component, objectID, key)
+	}
+
+	return map[string]entity{
+		topic("sensor", "next_event"):         nextEvent,
+		topic("sensor", "state"):              state,
+		topic("sensor", "start"):              start,
+		topic("binary_sensor", "in_progress"): inProgress,
+	}
+}
@@ -0,0 +1,213 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	apiv2 "github.com/quesurifn/ics-calendar-tidbyt-server/api/v2"
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultTopicPrefix     = "tidbyt-ics"
+	DefaultDiscoveryPrefix = "homeassistant"
+	// DefaultInterval is how often feeds are checked when no event is due to
+	// change sooner
+	DefaultInterval = time.Minute
+	// minWait keeps the publisher from spinning on an event that just ended
+	minWait = time.Second
+	// publishTimeout bounds how long a publish waits for the broker
+	publishTimeout = 10 * time.Second
+
+	online  = "online"
+	offline = "offline"
+)
+
+// Subscription is a feed whose next event state is published under its id
+type Subscription struct {
+	ID   string `required:"true"`
+	Name string // shown in Home Assistant, defaults to the id
+	// Feed is the feed to watch, in-progress events are always included
+	Feed t.IcsRequest `required:"true"`
+}
+
+// Config configures the broker connection and the topics
+type Config struct {
+	// Broker is the broker URL such as tcp://localhost:1883, publishing is off
+	// when it is empty. Tests can point it at an in-process broker.
+	Broker   string `url:"true"`
+	ClientID string
+	Username string
+	Password string `secret:"true"`
+	// TopicPrefix is the root of the state and availability topics
+	TopicPrefix string
+	// DiscoveryPrefix is the Home Assistant discovery prefix, discovery config
+	// is not published when Discovery is false
+	DiscoveryPrefix string
+	Discovery       bool
+	IntervalSeconds int `min:"0"`
+	Subscriptions   []Subscription
+}
+
+// Publisher writes the next event state of every subscription to retained
+// topics. The client reconnects on its own and republishes discovery config and
+// the last known states on every connect, so a restarted broker is repopulated.
+type Publisher struct {
+	Logger   *zap.Logger
+	Calendar *c.Calendar
+	Config   Config
+
+	client paho.Client
+	mu     sync.Mutex
+	states map[string]t.NextEventResponse
+}
+
+// AvailabilityTopic is where online and offline are published, offline is the
+// client's will so it is set when the connection drops
+func (p *Publisher) AvailabilityTopic() string {
+	return p.Config.TopicPrefix + "/status"
+}
+
+// StateTopic is where the next event state of a subscription is published in
+// its API v2 shape
+func (p *Publisher) StateTopic(id string) string {
+	return p.Config.TopicPrefix + "/" + id + "/state"
+}
+
+// Run connects to the broker and publishes until ctx is done. The first
+// connect is retried in the background like any reconnect, states found in the
+// meantime are published once it succeeds.
+func (p *Publisher) Run(ctx context.Context) {
+	if p.Config.TopicPrefix == "" {
+		p.Config.TopicPrefix = DefaultTopicPrefix
+	}
+	if p.Config.DiscoveryPrefix == "" {
+		p.Config.DiscoveryPrefix = DefaultDiscoveryPrefix
+	}
+	p.states = map[string]t.NextEventResponse{}
+
+	p.client = paho.NewClient(p.clientOptions())
+	p.client.Connect()
+	defer func() {
+		p.publish(p.AvailabilityTopic(), offline)
+		p.client.Disconnect(250)
+	}()
+
+	for {
+		wait := p.check(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (p *Publisher) clientOptions() *paho.ClientOptions {
+	opts := paho.NewClientOptions().
+		AddBroker(p.Config.Broker).
+		SetClientID(p.Config.ClientID).
+		SetUsername(p.Config.Username).
+		SetPassword(p.Config.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetMaxReconnectInterval(time.Minute).
+		SetWill(p.AvailabilityTopic(), offline, 1, true).
+		SetOnConnectHandler(func(paho.Client) {
+			p.Logger.Info("MQTT", zap.String("broker", p.Config.Broker), zap.String("status", "connected"))
+			go p.republish()
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			p.Logger.Warn("MQTT", zap.String("broker", p.Config.Broker), zap.Error(err))
+		})
+
+	if p.Config.ClientID == "" {
+		opts.SetClientID(DefaultTopicPrefix)
+	}
+	return opts
+}
+
+// republish restores the retained topics after a connect
+func (p *Publisher) republish() {
+	p.publish(p.AvailabilityTopic(), online)
+
+	if p.Config.Discovery {
+		for _, sub := range p.Config.Subscriptions {
+			for topic, config := range p.discovery(sub) {
+				p.publishJSON(topic, config)
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, state := range p.states {
+		p.publishJSON(p.StateTopic(id), apiv2.FromNextEvent(state))
+	}
+}
+
+// check publishes the states that changed and returns how long to wait
+// before the next check
+func (p *Publisher) check(now time.Time) time.Duration {
+	wait := DefaultInterval
+	if p.Config.IntervalSeconds > 0 {
+		wait = time.Duration(p.Config.IntervalSeconds) * time.Second
+	}
+
+	for _, sub := range p.Config.Subscriptions {
+		feed := sub.Feed
+		feed.ShowInProgress = true
+		next, err := p.Calendar.Next(feed)
+		if err != nil {
+			p.Logger.Warn("MQTT", zap.String("subscription", sub.ID), zap.Error(err))
+			continue
+		}
+
+		// hold the lock while publishing so republish never overwrites a
+		// newer state with an older one
+		p.mu.Lock()
+		if last, ok := p.states[sub.ID]; !ok || c.Changed(last, next) {
+			p.publishJSON(p.StateTopic(sub.ID), apiv2.FromNextEvent(next))
+		}
+		p.states[sub.ID] = next
+		p.mu.Unlock()
+
+		if until := time.Unix(next.RefreshAt, 0).Sub(now); until < wait {
+			wait = until
+		}
+	}
+
+	return max(wait, minWait)
+}
+
+func (p *Publisher) publishJSON(topic string, v interface{}) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		p.Logger.Error("MQTT", zap.String("topic", topic), zap.Error(err))
+		return
+	}
+	p.publish(topic, payload)
+}
+
+// publish writes a retained message, while the client is reconnecting it is
+// dropped and the state is restored by republish
+func (p *Publisher) publish(topic string, payload interface{}) {
+	if !p.client.IsConnectionOpen() {
+		return
+	}
+
+	token := p.client.Publish(topic, 1, true, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		p.Logger.Warn("MQTT", zap.String("topic", topic), zap.String("err", "publish timed out"))
+		return
+	}
+	if err := token.Error(); err != nil {
+		p.Logger.Warn("MQTT", zap.String("topic", topic), zap.Error(err))
+	}
+}
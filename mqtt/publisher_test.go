@@ -0,0 +1,188 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+const icsTime = "20060102T150405Z"
+
+// waitTimeout bounds how long a test waits for a message, reconnects back off
+// for a few seconds
+const waitTimeout = 15 * time.Second
+
+// startBroker runs an in-process broker on addr until stop is called or the
+// test ends
+func startBroker(t *testing.T, addr string) (broker *mochi.Server, stop func()) {
+	broker = mochi.New(&mochi.Options{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.AddListener(listeners.NewTCP("tcp", addr, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	var once sync.Once
+	stop = func() { once.Do(func() { broker.Close() }) }
+	t.Cleanup(stop)
+	return broker, stop
+}
+
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// feedServer serves a feed with one meeting an hour from now
+func feedServer(t *testing.T) string {
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Minute)
+	feed := fmt.Sprintf("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n"+
+		"BEGIN:VEVENT\r\nUID:standup\r\nDTSTAMP:%s\r\nSUMMARY:Standup\r\nDTSTART:%s\r\nDTEND:%s\r\nEND:VEVENT\r\n"+
+		"END:VCALENDAR\r\n", start.Format(icsTime), start.Format(icsTime), start.Add(30*time.Minute).Format(icsTime))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		io.WriteString(w, feed)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/cal.ics"
+}
+
+// recorder subscribes to every topic of a broker and keeps the messages
+type recorder struct {
+	mu       sync.Mutex
+	messages map[string][]string
+	// seen is how many messages of a topic earlier waits matched
+	seen map[string]int
+}
+
+func subscribe(t *testing.T, addr string) *recorder {
+	r := &recorder{messages: map[string][]string{}, seen: map[string]int{}}
+	client := paho.NewClient(paho.NewClientOptions().
+		AddBroker("tcp://" + addr).
+		SetClientID(fmt.Sprintf("test-%d", time.Now().UnixNano())))
+	if token := client.Connect(); !token.WaitTimeout(waitTimeout) || token.Error() != nil {
+		t.Fatalf("connect: %v", token.Error())
+	}
+	t.Cleanup(func() { client.Disconnect(0) })
+
+	token := client.Subscribe("#", 1, func(_ paho.Client, msg paho.Message) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.messages[msg.Topic()] = append(r.messages[msg.Topic()], string(msg.Payload()))
+	})
+	if !token.WaitTimeout(waitTimeout) || token.Error() != nil {
+		t.Fatalf("subscribe: %v", token.Error())
+	}
+	return r
+}
+
+// waitFor waits for a message on topic containing want, after the messages
+// earlier waits on the topic matched
+func (r *recorder) waitFor(t *testing.T, topic, want string) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		messages := r.messages[topic]
+		for i := r.seen[topic]; i < len(messages); i++ {
+			if strings.Contains(messages[i], want) {
+				r.seen[topic] = i + 1
+				r.mu.Unlock()
+				return
+			}
+		}
+		r.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.Fatalf("no message on %s containing %q, got %q", topic, want, r.messages[topic])
+}
+
+func TestPublisher(t *testing.T) {
+	addr := freeAddr(t)
+	broker, stopBroker := startBroker(t, addr)
+
+	logger := zap.NewNop()
+	publisher := &Publisher{
+		Logger:   logger,
+		Calendar: &calendar.Calendar{Logger: logger},
+		Config: Config{
+			Broker:          "tcp://" + addr,
+			Discovery:       true,
+			IntervalSeconds: 3600,
+			Subscriptions: []Subscription{
+				{ID: "office", Feed: types.IcsRequest{ICSUrl: feedServer(t)}},
+			},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.Run(ctx)
+
+	stateTopic := "tidbyt-ics/office/state"
+	statusTopic := "tidbyt-ics/status"
+	discoveryTopic := "homeassistant/sensor/tidbyt-ics_office/next_event/config"
+
+	t.Run("retained", func(t *testing.T) {
+		// A late subscriber only sees what the broker retained
+		r := subscribe(t, addr)
+		r.waitFor(t, statusTopic, online)
+		r.waitFor(t, stateTopic, `"rawName":"Standup"`)
+		r.waitFor(t, discoveryTopic, `"state_topic":"`+stateTopic+`"`)
+		r.waitFor(t, "homeassistant/binary_sensor/tidbyt-ics_office/in_progress/config", `"availability_topic":"`+statusTopic+`"`)
+	})
+
+	t.Run("will", func(t *testing.T) {
+		r := subscribe(t, addr)
+		r.waitFor(t, statusTopic, online)
+
+		// Dropping the connection without a disconnect publishes the will,
+		// then the client reconnects and announces itself again
+		client, ok := broker.Clients.Get(DefaultTopicPrefix)
+		if !ok {
+			t.Fatal("publisher is not connected")
+		}
+		client.Stop(errors.New("connection dropped"))
+		r.waitFor(t, statusTopic, offline)
+		r.waitFor(t, statusTopic, online)
+	})
+
+	t.Run("republish", func(t *testing.T) {
+		// A restarted broker has lost every retained message, the publisher
+		// restores them on reconnect without checking the feed again
+		stopBroker()
+		startBroker(t, addr)
+
+		r := subscribe(t, addr)
+		r.waitFor(t, statusTopic, online)
+		r.waitFor(t, stateTopic, `"rawName":"Standup"`)
+		r.waitFor(t, discoveryTopic, `"state_topic":"`+stateTopic+`"`)
+	})
+}
@@ -21,7 +21,13 @@ type Settings struct {
 	Silent             bool
 	AutoReload         bool
 	AutoReloadInterval time.Duration
+	// AutoReloadCallback receives a new configuration whenever the files
+	// change, the configuration passed to Load is left untouched so readers
+	// never see it half written
 	AutoReloadCallback func(cfg interface{})
+	// AutoReloadErrorCallback receives the errors of reloads, they are
+	// printed when it is nil
+	AutoReloadErrorCallback func(err error)
 
 	// In case of json files, this field will be used only when compiled with
 	// go 1.10 or later.
@@ -81,23 +87,28 @@ func (c *Config) Load(cfg interface{}, files ...string) (err error) {
 	if !defaultValue.CanAddr() {
 		return fmt.Errorf("Config %v should be addressable", cfg)
 	}
+	// Reloads start over from the values cfg held before loading
+	defaults := clone(defaultValue)
 	_, err = c.load(cfg, false, files...)
 
 	if c.AutoReload {
 		go func() {
 			timer := time.NewTimer(c.AutoReloadInterval)
 			for range timer.C {
-				reflectPtr := reflect.New(reflect.ValueOf(cfg).Elem().Type())
-				reflectPtr.Elem().Set(defaultValue)
+				reflectPtr := reflect.New(defaultValue.Type())
+				reflectPtr.Elem().Set(clone(defaults))
 
-				var changed bool
-				if changed, err = c.load(reflectPtr.Interface(), true, files...); err == nil && changed {
-					reflect.ValueOf(cfg).Elem().Set(reflectPtr.Elem())
+				if changed, err := c.load(reflectPtr.Interface(), true, files...); err == nil && changed {
 					if c.AutoReloadCallback != nil {
-						c.AutoReloadCallback(cfg)
+						c.AutoReloadCallback(reflectPtr.Interface())
 					}
 				} else if err != nil {
-					fmt.Printf("Failed to reload configuration from %v, got error %v\n", files, err)
+					err = fmt.Errorf("failed to reload configuration from %v: %w", files, err)
+					if c.AutoReloadErrorCallback != nil {
+						c.AutoReloadErrorCallback(err)
+					} else {
+						fmt.Println(err)
+					}
 				}
 				timer.Reset(c.AutoReloadInterval)
 			}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// mask replaces the values of secret fields in printouts
+const mask = "******"
+
+// lookupEnv returns the value of env, or the contents of the file named by
+// env_FILE, which is how Docker and Kubernetes mount secrets. source is the
+// variable the value came from.
+func lookupEnv(env string) (value, source string, err error) {
+	if value := os.Getenv(env); value != "" {
+		return value, env, nil
+	}
+	if path := os.Getenv(env + "_FILE"); path != "" {
+		value, err := readSecretFile(path)
+		return value, env + "_FILE", err
+	}
+	return "", "", nil
+}
+
+// readSecretFile reads a secret, dropping the trailing newline editors and
+// echo leave behind
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveReference returns the value a string refers to. Values written as
+// env:NAME are read from the environment variable NAME and values written as
+// file:PATH from the file at PATH, so secrets can stay out of config files.
+// file:// URLs are not references.
+func resolveReference(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "env:"):
+		name := strings.TrimPrefix(s, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(s, "file:") && !strings.HasPrefix(s, "file://"):
+		return readSecretFile(strings.TrimPrefix(s, "file:"))
+	}
+	return s, nil
+}
+
+// resolveReferences replaces the env: and file: references of the string
+// fields, slices and maps of cfg with the values they refer to
+func resolveReferences(cfg interface{}) error {
+	var errs []error
+	var resolve func(value reflect.Value, path string)
+	resolve = func(value reflect.Value, path string) {
+		switch value.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if !value.IsNil() {
+				resolve(value.Elem(), path)
+			}
+		case reflect.String:
+			if !value.CanSet() {
+				return
+			}
+			resolved, err := resolveReference(value.String())
+			if err != nil {
+				errs = append(errs, &FieldError{Path: path, Err: err})
+				return
+			}
+			value.SetString(resolved)
+		case reflect.Struct:
+			for i := 0; i < value.NumField(); i++ {
+				if value.Type().Field(i).IsExported() {
+					resolve(value.Field(i), joinPath(path, value.Type().Field(i).Name))
+				}
+			}
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < value.Len(); i++ {
+				resolve(value.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			}
+		case reflect.Map:
+			if value.Type().Elem().Kind() != reflect.String {
+				return
+			}
+			for _, key := range sortedMapKeys(value) {
+				resolved, err := resolveReference(value.MapIndex(key).String())
+				if err != nil {
+					errs = append(errs, &FieldError{Path: fmt.Sprintf("%s[%v]", path, key), Err: err})
+					continue
+				}
+				value.SetMapIndex(key, reflect.ValueOf(resolved).Convert(value.Type().Elem()))
+			}
+		}
+	}
+	resolve(reflect.Indirect(reflect.ValueOf(cfg)), "")
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// masked returns a copy of cfg for printing, with the fields tagged
+// secret:"true" masked
+func masked(cfg interface{}) interface{} {
+	value := reflect.ValueOf(cfg)
+	if !value.IsValid() {
+		return cfg
+	}
+	copied := clone(value)
+	maskSecrets(copied, false)
+	return copied.Interface()
+}
+
+func maskSecrets(value reflect.Value, secret bool) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			maskSecrets(value.Elem(), secret)
+		}
+	case reflect.String:
+		if secret && value.CanSet() && value.Len() > 0 {
+			value.SetString(mask)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if field := value.Type().Field(i); field.IsExported() {
+				maskSecrets(value.Field(i), secret || field.Tag.Get("secret") == "true")
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			maskSecrets(value.Index(i), secret)
+		}
+	case reflect.Map:
+		if secret && value.Type().Elem().Kind() == reflect.String {
+			for _, key := range value.MapKeys() {
+				value.SetMapIndex(key, reflect.ValueOf(mask).Convert(value.Type().Elem()))
+			}
+		}
+	}
+}
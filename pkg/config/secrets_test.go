@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type secretConfig struct {
+	Name    string
+	Token   string            `secret:"true"`
+	Keys    []string          `secret:"true"`
+	Headers map[string]string `secret:"true"`
+	Broker  secretBroker
+}
+
+type secretBroker struct {
+	URL      string
+	Password string `secret:"true"`
+}
+
+// writeFile writes data to a file named name in a temporary directory
+func writeFile(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// captureStdout returns what fn prints
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-out
+}
+
+func TestLookupEnv(t *testing.T) {
+	t.Setenv("SECRETTEST_TOKEN_FILE", writeFile(t, "token", "from-file\r\n"))
+
+	value, source, err := lookupEnv("SECRETTEST_TOKEN")
+	if err != nil || value != "from-file" || source != "SECRETTEST_TOKEN_FILE" {
+		t.Errorf("lookupEnv = %q, %q, %v, want from-file read from SECRETTEST_TOKEN_FILE", value, source, err)
+	}
+
+	// The variable itself wins over the file
+	t.Setenv("SECRETTEST_TOKEN", "from-env")
+	if value, source, _ := lookupEnv("SECRETTEST_TOKEN"); value != "from-env" || source != "SECRETTEST_TOKEN" {
+		t.Errorf("lookupEnv = %q, %q, want from-env read from SECRETTEST_TOKEN", value, source)
+	}
+
+	t.Setenv("SECRETTEST_MISSING_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, source, err := lookupEnv("SECRETTEST_MISSING"); !errors.Is(err, os.ErrNotExist) || source != "SECRETTEST_MISSING_FILE" {
+		t.Errorf("lookupEnv of a missing file = %q, %v, want a not exist error from SECRETTEST_MISSING_FILE", source, err)
+	}
+}
+
+func TestResolveReference(t *testing.T) {
+	t.Setenv("SECRETTEST_VALUE", "from-env")
+	t.Setenv("SECRETTEST_EMPTY", "")
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		err   string
+	}{
+		{name: "plain", value: "plain", want: "plain"},
+		{name: "env", value: "env:SECRETTEST_VALUE", want: "from-env"},
+		{name: "empty env", value: "env:SECRETTEST_EMPTY", want: ""},
+		{name: "unset env", value: "env:SECRETTEST_UNSET", err: "environment variable SECRETTEST_UNSET is not set"},
+		{name: "file", value: "file:" + writeFile(t, "plain", "from-file"), want: "from-file"},
+		{name: "file newline", value: "file:" + writeFile(t, "newline", "from-file\n"), want: "from-file"},
+		{name: "file crlf", value: "file:" + writeFile(t, "crlf", "from-file\r\n"), want: "from-file"},
+		{name: "file keeps inner newlines", value: "file:" + writeFile(t, "lines", "one\ntwo\n\n"), want: "one\ntwo"},
+		{name: "missing file", value: "file:" + filepath.Join(t.TempDir(), "missing"), err: "no such file"},
+		{name: "file url", value: "file:///etc/calendar.ics", want: "file:///etc/calendar.ics"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := resolveReference(test.value)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("resolveReference(%q) error = %v, want %q", test.value, err, test.err)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("resolveReference(%q) = %q, %v, want %q", test.value, got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadResolvesReferences(t *testing.T) {
+	t.Setenv("SECRETTEST_HEADER", "header-secret")
+	t.Setenv("SECRETTEST_BROKER_PASSWORD_FILE", writeFile(t, "password", "password-secret\n"))
+	file := writeFile(t, "config.yml", strings.Join([]string{
+		"name: server",
+		"token: file:" + writeFile(t, "token", "token-secret\n"),
+		"keys: [env:SECRETTEST_KEY]",
+		"headers: {Authorization: env:SECRETTEST_HEADER}",
+		"broker: {url: 'tcp://localhost:1883'}",
+	}, "\n"))
+	t.Setenv("SECRETTEST_KEY", "key-secret")
+
+	var cfg secretConfig
+	var err error
+	out := captureStdout(t, func() {
+		err = New(&Settings{ENVPrefix: "SECRETTEST", Debug: true, Verbose: true}).Load(&cfg, file)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Token != "token-secret" || cfg.Keys[0] != "key-secret" ||
+		cfg.Headers["Authorization"] != "header-secret" || cfg.Broker.Password != "password-secret" {
+		t.Errorf("secrets were not resolved: %+v", cfg)
+	}
+	for _, secret := range []string{"token-secret", "key-secret", "header-secret", "password-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("printout contains %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "SECRETTEST_BROKER_PASSWORD_FILE") || !strings.Contains(out, mask) {
+		t.Errorf("printout does not name the secret file or mask the secrets:\n%s", out)
+	}
+	if !strings.Contains(out, "tcp://localhost:1883") {
+		t.Errorf("printout hides fields that are not secret:\n%s", out)
+	}
+}
+
+func TestLoadReportsUnresolvedReferences(t *testing.T) {
+	file := writeFile(t, "config.yml", "token: env:SECRETTEST_UNSET\nheaders: {Authorization: env:SECRETTEST_UNSET}\n")
+
+	var cfg secretConfig
+	err := New(&Settings{ENVPrefix: "SECRETTEST"}).Load(&cfg, file)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Errors) != 2 {
+		t.Fatalf("Load error = %v, want both references reported", err)
+	}
+	for _, path := range []string{"Token: ", "Headers[Authorization]: "} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("Load error = %v, want %s reported", err, path)
+		}
+	}
+}
+
+func TestMasked(t *testing.T) {
+	cfg := &secretConfig{
+		Name:    "server",
+		Token:   "token-secret",
+		Keys:    []string{"key-secret"},
+		Headers: map[string]string{"Authorization": "header-secret"},
+		Broker:  secretBroker{URL: "tcp://localhost:1883", Password: "password-secret"},
+	}
+
+	got := masked(cfg).(*secretConfig)
+	want := secretConfig{
+		Name:    "server",
+		Token:   mask,
+		Keys:    []string{mask},
+		Headers: map[string]string{"Authorization": mask},
+		Broker:  secretBroker{URL: "tcp://localhost:1883", Password: mask},
+	}
+	if got.Name != want.Name || got.Token != want.Token || got.Keys[0] != want.Keys[0] ||
+		got.Headers["Authorization"] != want.Headers["Authorization"] || got.Broker != want.Broker {
+		t.Errorf("masked = %+v, want %+v", *got, want)
+	}
+
+	// The configuration itself keeps its secrets
+	if cfg.Token != "token-secret" || cfg.Keys[0] != "key-secret" ||
+		cfg.Headers["Authorization"] != "header-secret" || cfg.Broker.Password != "password-secret" {
+		t.Errorf("masked changed the configuration: %+v", *cfg)
+	}
+
+	// Blank secrets stay blank, so a missing secret is visible
+	if got := masked(secretConfig{Name: "server"}).(secretConfig); got.Token != "" || got.Broker.Password != "" {
+		t.Errorf("masked blank secrets = %+v, want them blank", got)
+	}
+}
+
+func TestValidateHidesSecretValues(t *testing.T) {
+	type config struct {
+		Mode    string   `oneof:"on off" secret:"true"`
+		Allowed []string `cidr:"true" secret:"true"`
+		Expiry  string   `duration:"true" secret:"true"`
+		Token   string   `regexp:"^ics_" secret:"true"`
+		Level   string   `oneof:"debug info"`
+	}
+
+	err := Validate(&config{
+		Mode:    "mode-secret",
+		Allowed: []string{"cidr-secret"},
+		Expiry:  "duration-secret",
+		Token:   "regexp-secret",
+		Level:   "loud",
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Errors) != 5 {
+		t.Fatalf("Validate error = %v, want every field reported", err)
+	}
+	if strings.Contains(err.Error(), "-secret") {
+		t.Errorf("Validate error quotes secret values: %v", err)
+	}
+	for _, want := range []string{
+		"Mode: the value must be one of on, off",
+		"Allowed[0]: the value is not a CIDR range or IP",
+		"Expiry: the value is not a duration",
+		"Token: the value must match ^ics_",
+		`Level: "loud" must be one of debug, info`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate error = %v, want %q", err, want)
+		}
+	}
+}
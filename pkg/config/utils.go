@@ -266,11 +266,15 @@ func (c *Config) processTags(cfg interface{}, prefixes ...string) error {
 			fmt.Printf("Trying to load field `%v` from env %v\n", fieldStruct.Name, strings.Join(envNames, ", "))
 		}
 
-		// Load From Shell ENV
+		// Load From Shell ENV, or from the file named by ENV_FILE
 		for _, env := range envNames {
-			if value := os.Getenv(env); value != "" {
+			value, source, err := lookupEnv(env)
+			if err != nil {
+				return fmt.Errorf("%v: %w", source, err)
+			}
+			if value != "" {
 				if c.Settings.Debug || c.Settings.Verbose {
-					fmt.Printf("Loading configuration for field `%v` from env %v...\n", fieldStruct.Name, env)
+					fmt.Printf("Loading configuration for field `%v` from env %v...\n", fieldStruct.Name, source)
 				}
 
 				switch reflect.Indirect(field).Kind() {
@@ -292,11 +296,6 @@ func (c *Config) processTags(cfg interface{}, prefixes ...string) error {
 			}
 		}
 
-		if isBlank := reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface()); isBlank && fieldStruct.Tag.Get("required") == "true" {
-			// return error if it is required but blank
-			return errors.New(fieldStruct.Name + " is required, but blank")
-		}
-
 		for field.Kind() == reflect.Ptr {
 			field = field.Elem()
 		}
@@ -345,12 +344,13 @@ func (c *Config) processTags(cfg interface{}, prefixes ...string) error {
 
 func (c *Config) load(cfg interface{}, watchMode bool, files ...string) (changed bool, err error) {
 	defer func() {
-		if c.Settings.Debug || c.Settings.Verbose {
+		// Watching prints nothing until a file changes
+		if changed && (c.Settings.Debug || c.Settings.Verbose) {
 			if err != nil {
 				fmt.Printf("Failed to load configuration from %v, got %v\n", files, err)
 			}
 
-			fmt.Printf("Configuration:\n  %#v\n", cfg)
+			fmt.Printf("Configuration:\n  %#v\n", masked(cfg))
 		}
 	}()
 
@@ -371,6 +371,9 @@ func (c *Config) load(cfg interface{}, watchMode bool, files ...string) (changed
 		}
 	}
 
+	// A file that fails to load is reported once, not on every check
+	c.cfgModTimes = cfgModTimeMap
+
 	// process defaults
 	if err := c.processDefaults(cfg); err != nil {
 		return true, err
@@ -384,13 +387,56 @@ func (c *Config) load(cfg interface{}, watchMode bool, files ...string) (changed
 			return true, err
 		}
 	}
-	c.cfgModTimes = cfgModTimeMap
 
 	if prefix := c.getENVPrefix(cfg); prefix == "-" {
 		err = c.processTags(cfg)
 	} else {
 		err = c.processTags(cfg, prefix)
 	}
+	if err != nil {
+		return true, err
+	}
+	if err := resolveReferences(cfg); err != nil {
+		return true, err
+	}
+
+	// Every violation of validation tags and hooks is reported together
+	return true, Validate(cfg)
+}
 
-	return true, err
+// clone returns a deep copy of v, so loading into the copy leaves the maps,
+// slices and pointers of v alone. Unexported fields are copied shallowly.
+func clone(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			out.Set(clone(v.Elem()).Addr())
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			out.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			iter := v.MapRange()
+			for iter.Next() {
+				out.SetMapIndex(iter.Key(), clone(iter.Value()))
+			}
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			out.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				out.Index(i).Set(clone(v.Index(i)))
+			}
+		}
+	case reflect.Struct:
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(clone(v.Field(i)))
+			}
+		}
+	default:
+		out.Set(v)
+	}
+	return out
 }
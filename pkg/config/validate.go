@@ -0,0 +1,327 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validator is implemented by configurations that check what tags cannot
+// express. Validate is called on every struct of a configuration that
+// implements it, after the tags of its fields were checked. It runs even when
+// they failed, so every violation is reported at once, and must not rely on
+// the tags having passed.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError is a violation of the tags of a field, Path is the full Go path
+// of the field such as JWT.PublicKey or RateLimit.Routes[0].Path
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError holds every violation found in a configuration
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "invalid configuration:\n  " + strings.Join(messages, "\n  ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// Validate checks cfg against the validation tags of its fields and the
+// Validate hooks of its structs, every violation is returned in one
+// ValidationError. Blank fields are only checked by required, so optional
+// fields may be left out:
+//
+//	Port     int    `required:"true" min:"1" max:"65535"`
+//	Level    string `oneof:"debug info warn error"`
+//	Interval int    `min:"0" max:"3600"`
+//	Broker   string `url:"true"`
+//	Proxies  []string `cidr:"true"`
+//	Timeout  string `duration:"true"`
+//
+// min and max bound numbers, and the length of strings, slices and maps. The
+// other tags apply to each element of slices and maps. cidr accepts a single
+// IP as well.
+func Validate(cfg interface{}) error {
+	v := &validator{}
+	v.walk(reflect.Indirect(reflect.ValueOf(cfg)), "")
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(path string, err error) {
+	v.errs = append(v.errs, &FieldError{Path: path, Err: err})
+}
+
+// walk checks the fields of a struct, then its Validate hook
+func (v *validator) walk(value reflect.Value, path string) {
+	if value.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		fieldStruct, field := value.Type().Field(i), value.Field(i)
+		if !fieldStruct.IsExported() {
+			continue
+		}
+		fieldPath := fieldStruct.Name
+		if fieldStruct.Anonymous && fieldStruct.Tag.Get("anonymous") == "true" {
+			fieldPath = ""
+		}
+		fieldPath = joinPath(path, fieldPath)
+
+		if !field.IsZero() {
+			v.checkTags(field, fieldStruct.Tag, fieldPath)
+		} else if fieldStruct.Tag.Get("required") == "true" {
+			v.fail(fieldPath, errors.New("is required, but blank"))
+		}
+		v.descend(field, fieldPath)
+	}
+
+	v.hook(value, path)
+}
+
+// descend walks the structs a field holds
+func (v *validator) descend(field reflect.Value, path string) {
+	for field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			return
+		}
+		field = field.Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.Struct:
+		v.walk(field, path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			v.descend(field.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		for _, key := range sortedMapKeys(field) {
+			v.descend(field.MapIndex(key), fmt.Sprintf("%s[%v]", path, key))
+		}
+	}
+}
+
+// hook calls the Validate hook of a struct, errors are reported under the
+// path of the struct unless they are field errors of their own
+func (v *validator) hook(value reflect.Value, path string) {
+	var validate Validator
+	if value.CanAddr() {
+		validate, _ = value.Addr().Interface().(Validator)
+	} else {
+		validate, _ = value.Interface().(Validator)
+	}
+	if validate == nil {
+		return
+	}
+
+	err := validate.Validate()
+	if err == nil {
+		return
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			v.fail(joinPath(path, fieldErr.Path), fieldErr.Err)
+			continue
+		}
+		v.fail(path, err)
+	}
+}
+
+// checkTags applies the validation tags of a field that is not blank
+func (v *validator) checkTags(field reflect.Value, tag reflect.StructTag, path string) {
+	field = reflect.Indirect(field)
+
+	for _, name := range []string{"min", "max"} {
+		param := tag.Get(name)
+		if param == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			v.fail(path, fmt.Errorf("invalid %s tag %q", name, param))
+			continue
+		}
+		size, unit, ok := measure(field)
+		if !ok {
+			continue
+		}
+		if name == "min" && size < bound {
+			v.fail(path, fmt.Errorf("must be at least %s%s", param, unit))
+		}
+		if name == "max" && size > bound {
+			v.fail(path, fmt.Errorf("must be at most %s%s", param, unit))
+		}
+	}
+
+	checks := elementChecks(tag)
+	if len(checks) == 0 {
+		return
+	}
+	switch field.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			v.checkElement(field.Index(i), checks, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		for _, key := range sortedMapKeys(field) {
+			v.checkElement(field.MapIndex(key), checks, fmt.Sprintf("%s[%v]", path, key))
+		}
+	default:
+		v.checkElement(field, checks, path)
+	}
+}
+
+func (v *validator) checkElement(value reflect.Value, checks []func(string) error, path string) {
+	value = reflect.Indirect(value)
+	if value.IsZero() {
+		return
+	}
+	s := fmt.Sprint(value.Interface())
+	for _, check := range checks {
+		if err := check(s); err != nil {
+			v.fail(path, err)
+		}
+	}
+}
+
+// measure returns what min and max compare a field by
+func measure(field reflect.Value) (float64, string, bool) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), "", true
+	case reflect.String:
+		return float64(len(field.String())), " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(field.Len()), " entries", true
+	}
+	return 0, "", false
+}
+
+// elementChecks returns the checks of the tags that apply to single values.
+// Their messages quote the value, unless the field is secret.
+func elementChecks(tag reflect.StructTag) []func(string) error {
+	var checks []func(string) error
+	quote := strconv.Quote
+	if tag.Get("secret") == "true" {
+		quote = func(string) string { return "the value" }
+	}
+
+	if param := tag.Get("oneof"); param != "" {
+		allowed := strings.Fields(param)
+		checks = append(checks, func(s string) error {
+			for _, a := range allowed {
+				if s == a {
+					return nil
+				}
+			}
+			return fmt.Errorf("%s must be one of %s", quote(s), strings.Join(allowed, ", "))
+		})
+	}
+	if tag.Get("url") == "true" {
+		checks = append(checks, func(s string) error {
+			u, err := url.Parse(s)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				// URLs may carry credentials, so the value is left out
+				return errors.New("is not an absolute URL")
+			}
+			return nil
+		})
+	}
+	if tag.Get("cidr") == "true" {
+		checks = append(checks, func(s string) error {
+			if net.ParseIP(s) != nil {
+				return nil
+			}
+			if _, _, err := net.ParseCIDR(s); err != nil {
+				return fmt.Errorf("%s is not a CIDR range or IP", quote(s))
+			}
+			return nil
+		})
+	}
+	if tag.Get("duration") == "true" {
+		checks = append(checks, func(s string) error {
+			if _, err := time.ParseDuration(s); err != nil {
+				return fmt.Errorf("%s is not a duration such as 30s or 5m", quote(s))
+			}
+			return nil
+		})
+	}
+	if param := tag.Get("regexp"); param != "" {
+		pattern, err := regexp.Compile(param)
+		checks = append(checks, func(s string) error {
+			if err != nil {
+				return fmt.Errorf("invalid regexp tag %q: %w", param, err)
+			}
+			if !pattern.MatchString(s) {
+				return fmt.Errorf("%s must match %s", quote(s), param)
+			}
+			return nil
+		})
+	}
+	return checks
+}
+
+func joinPath(path, name string) string {
+	if path == "" || name == "" {
+		return path + name
+	}
+	return path + "." + name
+}
+
+// sortedMapKeys returns the keys of a map in a stable order, so violations
+// are reported in the same order on every load
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	Name    string   `required:"true"`
+	Level   string   `oneof:"debug info warn error"`
+	Workers int      `min:"1" max:"8"`
+	Tags    []string `max:"2"`
+	Broker  string   `url:"true"`
+	Proxies []string `cidr:"true"`
+	Timeout string   `duration:"true"`
+	Routes  []testRoute
+	Store   testStore
+}
+
+type testRoute struct {
+	Path string `required:"true"`
+	Max  int    `min:"1"`
+}
+
+// testStore checks a rule its tags cannot express
+type testStore struct {
+	Dir  string
+	Keep int `min:"0" max:"10"`
+}
+
+func (s testStore) Validate() error {
+	if s.Keep > 0 && s.Dir == "" {
+		return &FieldError{Path: "Dir", Err: errors.New("is required when Keep is set")}
+	}
+	return nil
+}
+
+func validConfig() testConfig {
+	return testConfig{
+		Name:    "server",
+		Level:   "info",
+		Workers: 4,
+		Tags:    []string{"a", "b"},
+		Broker:  "tcp://localhost:1883",
+		Proxies: []string{"10.0.0.0/8", "192.168.1.1"},
+		Timeout: "30s",
+		Routes:  []testRoute{{Path: "/", Max: 10}},
+		Store:   testStore{Dir: "/var/lib/server", Keep: 3},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*testConfig)
+		want   []string
+	}{
+		{name: "valid", modify: func(*testConfig) {}},
+		{name: "blank optional fields", modify: func(c *testConfig) {
+			*c = testConfig{Name: "server"}
+		}},
+		{name: "required", modify: func(c *testConfig) { c.Name = "" }, want: []string{
+			"Name: is required, but blank",
+		}},
+		{name: "min", modify: func(c *testConfig) { c.Workers = -1 }, want: []string{
+			"Workers: must be at least 1",
+		}},
+		{name: "max", modify: func(c *testConfig) { c.Workers = 9 }, want: []string{
+			"Workers: must be at most 8",
+		}},
+		{name: "max entries", modify: func(c *testConfig) { c.Tags = []string{"a", "b", "c"} }, want: []string{
+			"Tags: must be at most 2 entries",
+		}},
+		{name: "oneof", modify: func(c *testConfig) { c.Level = "loud" }, want: []string{
+			`Level: "loud" must be one of debug, info, warn, error`,
+		}},
+		{name: "url", modify: func(c *testConfig) { c.Broker = "localhost:1883" }, want: []string{
+			"Broker: is not an absolute URL",
+		}},
+		{name: "url keeps credentials out", modify: func(c *testConfig) { c.Broker = "user:s3cr3t@broker" }, want: []string{
+			"Broker: is not an absolute URL",
+		}},
+		{name: "cidr", modify: func(c *testConfig) { c.Proxies = []string{"10.0.0.0/8", "10.0.0.0/33"} }, want: []string{
+			`Proxies[1]: "10.0.0.0/33" is not a CIDR range or IP`,
+		}},
+		{name: "duration", modify: func(c *testConfig) { c.Timeout = "30" }, want: []string{
+			`Timeout: "30" is not a duration such as 30s or 5m`,
+		}},
+		{name: "nested path", modify: func(c *testConfig) {
+			c.Routes = append(c.Routes, testRoute{Max: -1})
+		}, want: []string{
+			"Routes[1].Path: is required, but blank",
+			"Routes[1].Max: must be at least 1",
+		}},
+		{name: "hook", modify: func(c *testConfig) { c.Store.Dir = "" }, want: []string{
+			"Store.Dir: is required when Keep is set",
+		}},
+		{name: "hook runs after failed tags", modify: func(c *testConfig) {
+			c.Store = testStore{Keep: 20}
+		}, want: []string{
+			"Store.Keep: must be at most 10",
+			"Store.Dir: is required when Keep is set",
+		}},
+		{name: "aggregation", modify: func(c *testConfig) {
+			c.Name = ""
+			c.Level = "loud"
+			c.Workers = 100
+			c.Proxies = []string{"nope"}
+			c.Routes[0].Path = ""
+			c.Store.Dir = ""
+		}, want: []string{
+			"Name: is required, but blank",
+			`Level: "loud" must be one of debug, info, warn, error`,
+			"Workers: must be at most 8",
+			`Proxies[0]: "nope" is not a CIDR range or IP`,
+			"Routes[0].Path: is required, but blank",
+			"Store.Dir: is required when Keep is set",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := validConfig()
+			test.modify(&cfg)
+
+			err := Validate(&cfg)
+			if test.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("got %v, want a ValidationError", err)
+			}
+			var got []string
+			for _, err := range validationErr.Errors {
+				var fieldErr *FieldError
+				if !errors.As(err, &fieldErr) {
+					t.Errorf("%v is not a FieldError", err)
+				}
+				got = append(got, err.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("got errors\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(test.want, "\n  "))
+			}
+		})
+	}
+}
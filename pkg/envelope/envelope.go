@@ -0,0 +1,211 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefix marks sealed values, values without it are plaintext written before
+// encryption was configured
+const prefix = "enc:v1:"
+
+var (
+	ErrNoKeyring  = errors.New("value is encrypted but no master key is configured")
+	ErrUnknownKey = errors.New("value is encrypted with an unknown master key")
+	ErrMalformed  = errors.New("malformed encrypted value")
+)
+
+var encoding = base64.RawStdEncoding
+
+// Keyring seals values with envelope encryption: every value gets a fresh
+// AES-256-GCM data key, which is stored wrapped by a master key next to the
+// value. The first master key seals, every key opens, so a master key is
+// rotated by putting the new key first, re-encrypting and dropping the old one.
+//
+// A nil Keyring leaves values in plaintext.
+type Keyring struct {
+	keys []masterKey
+}
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Parse reads master keys written as id:base64 pairs, separated by commas or
+// new lines. Keys are 32 random bytes, e.g. from openssl rand -base64 32.
+func Parse(spec string) (*Keyring, error) {
+	k := &Keyring{}
+	seen := map[string]bool{}
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("master key %q: expected id:base64", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("master key %q: duplicate id", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("master key %q: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("master key %q: must be 32 bytes, got %d", id, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		k.keys = append(k.keys, masterKey{id: id, aead: aead})
+		seen[id] = true
+	}
+
+	if len(k.keys) == 0 {
+		return nil, errors.New("no master keys")
+	}
+	return k, nil
+}
+
+// Load reads the master keys from spec, or from the file at path when spec is
+// empty. It returns a nil Keyring when both are empty.
+func Load(spec, path string) (*Keyring, error) {
+	if spec == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		spec = string(data)
+	}
+	if spec == "" {
+		return nil, nil
+	}
+	return Parse(spec)
+}
+
+// Seal encrypts a value with a new data key wrapped by the first master key
+func (k *Keyring) Seal(plaintext string) (string, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	master := k.keys[0]
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(master.aead, dataKey, []byte(master.id))
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, []byte(plaintext), []byte(master.id))
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + master.id + ":" + encoding.EncodeToString(wrapped) + ":" + encoding.EncodeToString(ciphertext), nil
+}
+
+// Open decrypts a sealed value, plaintext values are returned as they are
+func (k *Keyring) Open(value string) (string, error) {
+	if !Sealed(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrNoKeyring
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	id := parts[0]
+	wrapped, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	ciphertext, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	for _, master := range k.keys {
+		if master.id != id {
+			continue
+		}
+		dataKey, err := open(master.aead, wrapped, []byte(id))
+		if err != nil {
+			return "", err
+		}
+		aead, err := newAEAD(dataKey)
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := open(aead, ciphertext, []byte(id))
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+}
+
+// Current reports whether Seal would leave a value as it is: blank values,
+// values sealed with the first master key, and any plaintext when k is nil.
+// Values it reports false for are sealed again when their store is saved.
+func (k *Keyring) Current(value string) bool {
+	if value == "" {
+		return true
+	}
+	if k == nil {
+		return !Sealed(value)
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return Sealed(value) && id == k.keys[0].id
+}
+
+// Sealed reports whether a value is encrypted
+func Sealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce, which is put in front of the ciphertext
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additional)
+}
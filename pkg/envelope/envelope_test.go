@@ -0,0 +1,198 @@
+package envelope
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T, id string) string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(b)
+}
+
+func mustParse(t *testing.T, spec string) *Keyring {
+	k, err := Parse(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSealOpen(t *testing.T) {
+	k := mustParse(t, newKey(t, "a"))
+	const plaintext = "https://calendar.example.com/private-s3cret/basic.ics"
+
+	sealed, err := k.Seal(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Sealed(sealed) || strings.Contains(sealed, "s3cret") || !strings.HasPrefix(sealed, prefix+"a:") {
+		t.Errorf("Seal = %q, want a value sealed with key a", sealed)
+	}
+	again, _ := k.Seal(plaintext)
+	if again == sealed {
+		t.Error("Seal returned the same value twice, data keys or nonces are reused")
+	}
+
+	if got, err := k.Open(sealed); err != nil || got != plaintext {
+		t.Errorf("Open = %q, %v, want %q", got, err, plaintext)
+	}
+	// Values written before encryption was configured are read as they are
+	if got, err := k.Open(plaintext); err != nil || got != plaintext {
+		t.Errorf("Open of plaintext = %q, %v, want it unchanged", got, err)
+	}
+	if got, _ := k.Seal(""); got != "" {
+		t.Errorf("Seal of a blank value = %q, want it blank", got)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	old, next := newKey(t, "old"), newKey(t, "new")
+	sealed, err := mustParse(t, old).Seal("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the new key first, the old one still opens
+	rotating := mustParse(t, next+","+old)
+	if got, err := rotating.Open(sealed); err != nil || got != "secret" {
+		t.Errorf("Open with the old key second = %q, %v, want secret", got, err)
+	}
+	if rotating.Current(sealed) {
+		t.Error("Current reports a value sealed with the old key as current")
+	}
+	resealed, err := rotating.Seal("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resealed, prefix+"new:") || !rotating.Current(resealed) {
+		t.Errorf("Seal = %q, want it sealed with the first key", resealed)
+	}
+
+	// Once the old key is dropped its values cannot be opened
+	rotated := mustParse(t, next)
+	if _, err := rotated.Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open with the old key dropped error = %v, want ErrUnknownKey", err)
+	}
+	if got, err := rotated.Open(resealed); err != nil || got != "secret" {
+		t.Errorf("Open of the resealed value = %q, %v, want secret", got, err)
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	a, b := newKey(t, "a"), newKey(t, "b")
+	k := mustParse(t, a+","+b)
+	sealed, err := k.Seal("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(sealed, prefix), ":")
+	ciphertext, _ := encoding.DecodeString(parts[2])
+	ciphertext[len(ciphertext)-1] ^= 1
+
+	tests := []struct {
+		name  string
+		value string
+		err   error
+	}{
+		{name: "tampered ciphertext", value: prefix + parts[0] + ":" + parts[1] + ":" + encoding.EncodeToString(ciphertext)},
+		// The key id is authenticated, a value cannot claim another key
+		{name: "other key id", value: prefix + "b:" + parts[1] + ":" + parts[2]},
+		{name: "unknown key id", value: prefix + "c:" + parts[1] + ":" + parts[2], err: ErrUnknownKey},
+		{name: "missing part", value: prefix + "a:" + parts[1], err: ErrMalformed},
+		{name: "not base64", value: prefix + "a:!!:" + parts[2], err: ErrMalformed},
+		{name: "short", value: prefix + "a:" + parts[1] + ":AA", err: ErrMalformed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := k.Open(test.value)
+			if err == nil {
+				t.Fatalf("Open = %q, want an error", got)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Errorf("Open error = %v, want %v", err, test.err)
+			}
+		})
+	}
+
+	var none *Keyring
+	if _, err := none.Open(sealed); !errors.Is(err, ErrNoKeyring) {
+		t.Errorf("Open without keys error = %v, want ErrNoKeyring", err)
+	}
+	if got, _ := none.Seal("secret"); got != "secret" {
+		t.Errorf("Seal without keys = %q, want plaintext", got)
+	}
+	if !none.Current("secret") || none.Current(sealed) {
+		t.Error("Current without keys must accept plaintext only")
+	}
+}
+
+func TestParse(t *testing.T) {
+	key := newKey(t, "a")
+	_, encoded, _ := strings.Cut(key, ":")
+
+	tests := []struct {
+		name string
+		spec string
+		ids  []string
+		err  string
+	}{
+		{name: "one", spec: key, ids: []string{"a"}},
+		{name: "commas", spec: key + "," + strings.Replace(key, "a:", "b:", 1), ids: []string{"a", "b"}},
+		{name: "lines and comments", spec: "# current\n" + key + "\n\n" + strings.Replace(key, "a:", "b:", 1) + "\n", ids: []string{"a", "b"}},
+		{name: "no id", spec: ":" + encoded, err: "expected id:base64"},
+		{name: "no separator", spec: encoded, err: "expected id:base64"},
+		{name: "duplicate", spec: key + "," + key, err: "duplicate id"},
+		{name: "short", spec: "a:" + base64.StdEncoding.EncodeToString([]byte("short")), err: "must be 32 bytes"},
+		{name: "not base64", spec: "a:!!", err: "illegal base64"},
+		{name: "empty", spec: "# nothing\n", err: "no master keys"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			k, err := Parse(test.spec)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("Parse error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, master := range k.keys {
+				ids = append(ids, master.id)
+			}
+			if strings.Join(ids, ",") != strings.Join(test.ids, ",") {
+				t.Errorf("Parse ids = %v, want %v", ids, test.ids)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if k, err := Load("", ""); k != nil || err != nil {
+		t.Errorf("Load of nothing = %v, %v, want a nil keyring", k, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte(newKey(t, "file")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := Load("", path)
+	if err != nil || k.keys[0].id != "file" {
+		t.Errorf("Load of the key file = %v, %v, want key file", k, err)
+	}
+	// Keys win over the file
+	if k, err := Load(newKey(t, "spec"), path); err != nil || k.keys[0].id != "spec" {
+		t.Errorf("Load = %v, %v, want key spec", k, err)
+	}
+}
@@ -0,0 +1,86 @@
+package glyph
+
+import "unicode"
+
+// emoji pairs a shortcode and its symbols with the ASCII tag shown on the display
+type emoji struct {
+	Shortcode string
+	Runes     []rune
+	Tag       string
+}
+
+var emojis = []emoji{
+	{":coffee:", []rune{'☕'}, "[cof]"},
+	{":calendar:", []rune{'📅', '📆', '🗓'}, "[cal]"},
+	{":phone:", []rune{'📞', '☎', '📱'}, "[tel]"},
+	{":video_camera:", []rune{'📹', '🎥'}, "[vid]"},
+	{":computer:", []rune{'💻', '🖥'}, "[pc]"},
+	{":airplane:", []rune{'✈', '🛫', '🛬'}, "[fly]"},
+	{":car:", []rune{'🚗', '🚙'}, "[car]"},
+	{":house:", []rune{'🏠', '🏡'}, "[home]"},
+	{":hospital:", []rune{'🏥', '🩺'}, "[dr]"},
+	{":pill:", []rune{'💊'}, "[med]"},
+	{":tooth:", []rune{'🦷'}, "[dds]"},
+	{":birthday:", []rune{'🎂', '🧁'}, "[bday]"},
+	{":tada:", []rune{'🎉', '🎊', '🥳'}, "[party]"},
+	{":gift:", []rune{'🎁'}, "[gift]"},
+	{":pizza:", []rune{'🍕'}, "[food]"},
+	{":fork_and_knife:", []rune{'🍴', '🍽'}, "[food]"},
+	{":beer:", []rune{'🍺', '🍻', '🍷', '🍸'}, "[drink]"},
+	{":soccer:", []rune{'⚽'}, "[socr]"},
+	{":basketball:", []rune{'🏀'}, "[bball]"},
+	{":running:", []rune{'🏃'}, "[run]"},
+	{":weight_lifting:", []rune{'🏋'}, "[gym]"},
+	{":school:", []rune{'🏫', '🎓'}, "[sch]"},
+	{":books:", []rune{'📚', '📖'}, "[book]"},
+	{":memo:", []rune{'📝', '✏'}, "[note]"},
+	{":pushpin:", []rune{'📌', '📍'}, "[pin]"},
+	{":alarm_clock:", []rune{'⏰', '⏱', '⌚'}, "[time]"},
+	{":bell:", []rune{'🔔'}, "[bell]"},
+	{":warning:", []rune{'⚠'}, "[!]"},
+	{":white_check_mark:", []rune{'✅', '✔'}, "[ok]"},
+	{":star:", []rune{'⭐', '🌟'}, "[*]"},
+	{":heart:", []rune{'❤', '💕', '💖'}, "<3"},
+	{":fire:", []rune{'🔥'}, "[hot]"},
+	{":rocket:", []rune{'🚀'}, "[ship]"},
+	{":bulb:", []rune{'💡'}, "[idea]"},
+	{":handshake:", []rune{'🤝'}, "[mtg]"},
+	{":wave:", []rune{'👋'}, "[hi]"},
+	{":dog:", []rune{'🐶', '🐕'}, "[dog]"},
+	{":cat:", []rune{'🐱', '🐈'}, "[cat]"},
+	{":sunny:", []rune{'☀', '🌞'}, "[sun]"},
+	{":money_bag:", []rune{'💰', '💵'}, "[$]"},
+	{":musical_note:", []rune{'🎵', '🎶'}, "[music]"},
+	{":lock:", []rune{'🔒', '🔐'}, "[lock]"},
+}
+
+var (
+	emojiTags     = map[rune]string{}
+	shortcodeTags = map[string]string{}
+)
+
+func init() {
+	for _, e := range emojis {
+		shortcodeTags[e.Shortcode] = e.Tag
+		for _, r := range e.Runes {
+			emojiTags[r] = e.Tag
+		}
+	}
+}
+
+// isEmoji reports whether the rune is a pictograph or one of the modifiers
+// that combine with pictographs into a single glyph
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // pictographs, symbols, flags and skin tones
+		r >= 0x2300 && r <= 0x23FF,   // misc technical (watches, hourglasses)
+		r >= 0x2600 && r <= 0x27BF,   // misc symbols and dingbats
+		r >= 0x2B00 && r <= 0x2BFF,   // stars, arrows and squares
+		r >= 0xE0020 && r <= 0xE007F, // tag sequences
+		r == 0x200D,                  // zero width joiner
+		r == 0x20E3,                  // combining keycap
+		unicode.Is(unicode.Variation_Selector, r):
+		return true
+	}
+	return false
+}
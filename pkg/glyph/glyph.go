@@ -0,0 +1,74 @@
+package glyph
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Options controls how text the Tidbyt fonts cannot draw is rewritten
+type Options struct {
+	// Placeholder replaces each run of characters from unsupported scripts
+	// (CJK, Cyrillic, Arabic, ...). When empty those characters are dropped.
+	Placeholder string
+}
+
+// latin maps letters and punctuation that do not decompose into plain ASCII
+var latin = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE",
+	'ø': "o", 'Ø': "O", 'ł': "l", 'Ł': "L", 'đ': "d", 'Đ': "D",
+	'ð': "d", 'Ð': "D", 'þ': "th", 'Þ': "Th", 'ı': "i", 'ħ': "h", 'Ħ': "H",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '−': "-",
+	'‘': "'", '’': "'", '‚': "'", '′': "'", '“': "\"", '”': "\"", '„': "\"", '″': "\"",
+	'«': "<<", '»': ">>", '‹': "<", '›': ">",
+	'…': "...", '•': "*", '·': "*", '×': "x", '÷': "/",
+	'©': "(c)", '®': "(R)", '™': "TM", '°': "o",
+	'€': "EUR", '£': "GBP", '¥': "JPY", '¢': "c",
+	'¿': "?", '¡': "!",
+}
+
+var shortcodes *strings.Replacer
+
+func init() {
+	var pairs []string
+	for code, tag := range shortcodeTags {
+		pairs = append(pairs, code, tag)
+	}
+	shortcodes = strings.NewReplacer(pairs...)
+}
+
+// Sanitize rewrites text into the ASCII subset every Tidbyt font can draw.
+// Known emoji and shortcodes become short tags, accented Latin letters lose
+// their accents and everything else is dropped or replaced by the placeholder.
+func Sanitize(text string, opts Options) string {
+	var b strings.Builder
+	placeheld := false
+
+	for _, r := range norm.NFD.String(shortcodes.Replace(text)) {
+		switch {
+		case r < unicode.MaxASCII && !unicode.IsControl(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			b.WriteRune(' ')
+		case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r):
+			// Combining marks and invisible format characters draw nothing
+			continue
+		case emojiTags[r] != "":
+			b.WriteString(" " + emojiTags[r] + " ")
+		case latin[r] != "":
+			b.WriteString(latin[r])
+		case isEmoji(r):
+			continue
+		default:
+			if opts.Placeholder != "" && !placeheld {
+				b.WriteString(opts.Placeholder)
+			}
+			placeheld = true
+			continue
+		}
+		placeheld = false
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
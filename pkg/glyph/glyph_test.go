@@ -0,0 +1,52 @@
+package glyph
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		placeholder string
+		want        string
+	}{
+		{name: "ascii", text: "Standup @ 9:30 (room 4)", want: "Standup @ 9:30 (room 4)"},
+		{name: "accents", text: "Café crème à Zürich", want: "Cafe creme a Zurich"},
+		{name: "stacked accents", text: "ä́", want: "a"},
+		{name: "latin letters", text: "Straße Øresund Łódź", want: "Strasse Oresund Lodz"},
+		{name: "punctuation", text: "Q&A — “all hands”…", want: "Q&A - \"all hands\"..."},
+		{name: "whitespace", text: "Tab\there\nnew  line", want: "Tab here new line"},
+		{name: "emoji", text: "Lunch ☕", want: "Lunch [cof]"},
+		{name: "emoji without space", text: "🎂Party", want: "[bday] Party"},
+		{name: "shortcode", text: "Lunch :coffee:", want: "Lunch [cof]"},
+		{name: "unknown emoji", text: "Meeting 🦄 now", want: "Meeting now"},
+		{name: "emoji sequence", text: "Team 🧑‍🦰 sync", want: "Team sync"},
+		{name: "format characters", text: "​zero‎width", placeholder: "?", want: "zerowidth"},
+		{name: "unknown script dropped", text: "会议 Standup", want: "Standup"},
+		{name: "unknown script placeholder", text: "会议 Standup", placeholder: "?", want: "? Standup"},
+		{name: "one placeholder per run", text: "Sync 日本語テキスト now", placeholder: "?", want: "Sync ? now"},
+		{name: "run inside a word", text: "Ab会议cd", placeholder: "?", want: "Ab?cd"},
+		{name: "run across marks and emoji", text: "会́🦄议", placeholder: "?", want: "?"},
+		{name: "runs split by spaces", text: "Встреча в офисе", placeholder: "?", want: "? ? ?"},
+		{name: "wide placeholder", text: "会议 Standup", placeholder: "[?]", want: "[?] Standup"},
+		{name: "nothing drawable", text: "日本語", want: ""},
+		{name: "empty", text: "", placeholder: "?", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Sanitize(test.text, Options{Placeholder: test.placeholder})
+			if got != test.want {
+				t.Errorf("Sanitize(%q, %q) = %q, want %q", test.text, test.placeholder, got, test.want)
+			}
+		})
+	}
+}
+
+func TestShortcodesMatchEmoji(t *testing.T) {
+	for _, e := range emojis {
+		for _, r := range e.Runes {
+			if got, want := Sanitize(string(r), Options{}), Sanitize(e.Shortcode, Options{}); got != want {
+				t.Errorf("%q sanitizes to %q, its shortcode %s to %q", r, got, e.Shortcode, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxLineOctets is the longest content line RFC 5545 allows, not counting the CRLF
+	maxLineOctets = 75
+	crlf          = "\r\n"
+
+	// DateTimeFormat is a local date-time, DateTimeUTCFormat one in UTC
+	DateTimeFormat    = "20060102T150405"
+	DateTimeUTCFormat = "20060102T150405Z"
+)
+
+// Param is a property parameter such as TZID=America/Chicago
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Writer writes iCalendar content lines, folding them at 75 octets
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Begin opens a component such as VCALENDAR or VEVENT
+func (w *Writer) Begin(component string) {
+	w.Property("BEGIN", component)
+}
+
+// End closes a component
+func (w *Writer) End(component string) {
+	w.Property("END", component)
+}
+
+// Property writes a property whose value is already encoded, use Text for
+// free text values
+func (w *Writer) Property(name, value string, params ...Param) {
+	var line strings.Builder
+	line.WriteString(name)
+	for _, p := range params {
+		line.WriteString(";" + p.Name + "=" + quoteParam(p.Value))
+	}
+	line.WriteString(":" + value)
+
+	w.write(fold(line.String()))
+}
+
+// Flush writes any buffered lines and returns the first error seen
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) write(s string) {
+	if w.err == nil {
+		_, w.err = w.w.WriteString(s)
+	}
+}
+
+// Text escapes a TEXT value
+func Text(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// List escapes and joins the values of a multi-valued TEXT property such as CATEGORIES
+func List(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = Text(v)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// UTC formats a time as a UTC date-time
+func UTC(t time.Time) string {
+	return t.UTC().Format(DateTimeUTCFormat)
+}
+
+// Local formats a time as a date-time in its own location, to be used with a TZID parameter
+func Local(t time.Time) string {
+	return t.Format(DateTimeFormat)
+}
+
+// quoteParam quotes parameter values that contain separators
+func quoteParam(v string) string {
+	if strings.ContainsAny(v, ";:,") {
+		return `"` + strings.ReplaceAll(v, `"`, "") + `"`
+	}
+	return v
+}
+
+// fold splits a content line into lines of at most 75 octets, continuation
+// lines start with a space. Multi-byte characters are never split.
+func fold(line string) string {
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + crlf + " ")
+		line = line[cut:]
+		// the leading space counts towards the next line
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line + crlf)
+	return b.String()
+}
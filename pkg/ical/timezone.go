@@ -0,0 +1,77 @@
+package ical
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timezone writes a VTIMEZONE for loc that covers from to to. Go does not expose
+// the rules of a zone, so every offset change in the range is written as its
+// own observance, preceded by the observance in effect at from.
+func (w *Writer) Timezone(loc *time.Location, from, to time.Time) {
+	w.Begin("VTIMEZONE")
+	w.Property("TZID", loc.String())
+
+	start := from.In(loc)
+	name, offset := start.Zone()
+	w.observance(start.IsDST(), time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), name, offset, offset)
+
+	for _, tr := range transitions(loc, from, to) {
+		name, toOffset := tr.Zone()
+		// DTSTART is the local time of the change, read on the clock it replaces
+		onset := tr.UTC().Add(time.Duration(offset) * time.Second)
+		w.observance(tr.IsDST(), onset, name, offset, toOffset)
+		offset = toOffset
+	}
+
+	w.End("VTIMEZONE")
+}
+
+func (w *Writer) observance(dst bool, onset time.Time, name string, from, to int) {
+	component := "STANDARD"
+	if dst {
+		component = "DAYLIGHT"
+	}
+
+	w.Begin(component)
+	w.Property("DTSTART", onset.Format(DateTimeFormat))
+	w.Property("TZOFFSETFROM", utcOffset(from))
+	w.Property("TZOFFSETTO", utcOffset(to))
+	w.Property("TZNAME", Text(name))
+	w.End(component)
+}
+
+// transitions returns the instants in (from, to] at which loc changes its
+// offset, found by checking every day and narrowing changes down to the second
+func transitions(loc *time.Location, from, to time.Time) []time.Time {
+	var changes []time.Time
+
+	_, offset := from.In(loc).Zone()
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if _, nextOffset := next.In(loc).Zone(); nextOffset != offset {
+			lo, hi := day, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.In(loc).Zone(); o == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			changes = append(changes, hi.In(loc).Truncate(time.Second))
+			offset = nextOffset
+		}
+	}
+
+	return changes
+}
+
+// utcOffset formats an offset in seconds as +HHMM
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
@@ -0,0 +1,38 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// ErrLocked reports a file another process holds the lock of
+var ErrLocked = errors.New("file is locked by another process")
+
+// Load reads the JSON file at path into v. A missing file leaves v untouched
+// and is not an error.
+func Load(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Save writes v to path as JSON. It writes to a temporary file first and
+// renames it into place, so a crash never leaves a torn file behind.
+func Save(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
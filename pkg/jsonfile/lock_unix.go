@@ -0,0 +1,28 @@
+//go:build unix
+
+package jsonfile
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Lock takes an exclusive lock on path.lock, so a file is changed by a single
+// process at a time. It fails with ErrLocked when another process, or another
+// store of this one, holds the lock. The lock is released by the returned
+// function or when the process exits, a crash never leaves it behind.
+func Lock(path string) (unlock func() error, err error) {
+	file, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return file.Close, nil
+}
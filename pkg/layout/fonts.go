@@ -0,0 +1,72 @@
+package layout
+
+// Font holds the pixel metrics of a Tidbyt bitmap font
+type Font struct {
+	Name    string
+	Height  int
+	Advance int
+	widths  map[rune]int
+}
+
+// Width returns the number of pixels the text occupies when rendered in the font
+func (f Font) Width(text string) int {
+	width := 0
+	for _, r := range text {
+		width += f.RuneWidth(r)
+	}
+	return width
+}
+
+// RuneWidth returns the advance of a single rune, including its trailing spacing
+func (f Font) RuneWidth(r rune) int {
+	if w, ok := f.widths[r]; ok {
+		return w
+	}
+	return f.Advance
+}
+
+// TomThumb is the 4x6 font used for most small labels
+var TomThumb = Font{
+	Name:    "tom-thumb",
+	Height:  6,
+	Advance: 4,
+	widths: map[rune]int{
+		' ': 2, '.': 2, ',': 2, ':': 2, ';': 2, '!': 2, '\'': 2, '|': 2,
+		'i': 2, 'l': 3, '(': 3, ')': 3, '[': 3, ']': 3,
+	},
+}
+
+// TB8 is the proportional 8 pixel font pixlet uses by default
+var TB8 = Font{
+	Name:    "tb-8",
+	Height:  8,
+	Advance: 5,
+	widths: map[rune]int{
+		' ': 3, '.': 2, ',': 2, ':': 2, ';': 2, '!': 2, '\'': 2, '|': 2, '`': 3,
+		'i': 2, 'l': 2, 'j': 3, 't': 4, 'f': 4, 'r': 4, 'I': 4, '1': 4,
+		'(': 3, ')': 3, '[': 3, ']': 3,
+		'm': 6, 'w': 6, 'M': 6, 'W': 6,
+	},
+}
+
+// Font5x8 is the fixed width 5x8 font
+var Font5x8 = Font{
+	Name:    "5x8",
+	Height:  8,
+	Advance: 5,
+}
+
+// Font6x13 is the fixed width 6x13 font used for large headings
+var Font6x13 = Font{
+	Name:    "6x13",
+	Height:  13,
+	Advance: 6,
+}
+
+// Fonts lists every supported font by its pixlet name
+var Fonts = map[string]Font{
+	TomThumb.Name: TomThumb,
+	TB8.Name:      TB8,
+	Font5x8.Name:  Font5x8,
+	Font6x13.Name: Font6x13,
+}
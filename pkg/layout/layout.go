@@ -0,0 +1,203 @@
+package layout
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// DisplayWidth is the width of the Tidbyt LED matrix in pixels
+	DisplayWidth = 64
+	// DefaultMaxLines is the number of lines an event name may wrap onto
+	DefaultMaxLines = 2
+	// Ellipsis marks text that has been truncated
+	Ellipsis = "..."
+)
+
+// Abbreviations maps lower case words to the short form used when text does not fit
+var Abbreviations = map[string]string{
+	"and":           "&",
+	"appointment":   "Appt",
+	"business":      "Biz",
+	"committee":     "Cmte",
+	"conference":    "Conf",
+	"department":    "Dept",
+	"development":   "Dev",
+	"discussion":    "Disc",
+	"doctor":        "Dr",
+	"engineering":   "Eng",
+	"executive":     "Exec",
+	"information":   "Info",
+	"international": "Intl",
+	"interview":     "Intvw",
+	"leadership":    "Ldrshp",
+	"management":    "Mgmt",
+	"marketing":     "Mktg",
+	"meeting":       "Mtg",
+	"monthly":       "Mthly",
+	"operations":    "Ops",
+	"planning":      "Plng",
+	"presentation":  "Pres",
+	"project":       "Proj",
+	"quarterly":     "Qtrly",
+	"review":        "Rvw",
+	"training":      "Trng",
+	"weekly":        "Wkly",
+	"workshop":      "Wkshp",
+}
+
+// Abbreviate replaces every word found in the abbreviation dictionary
+func Abbreviate(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		core := strings.TrimRightFunc(word, unicode.IsPunct)
+		short, ok := Abbreviations[strings.ToLower(core)]
+		if !ok {
+			continue
+		}
+		if core == strings.ToLower(core) {
+			short = strings.ToLower(short)
+		}
+		words[i] = short + word[len(core):]
+	}
+	return strings.Join(words, " ")
+}
+
+// Fit shortens text so it renders on a single line of width pixels
+func Fit(text string, font Font, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if font.Width(text) <= width {
+		return text
+	}
+
+	text = Abbreviate(text)
+	if font.Width(text) <= width {
+		return text
+	}
+
+	return truncate(strings.Fields(text), font, width)
+}
+
+// Wrap breaks text into at most maxLines lines of width pixels, abbreviating
+// and truncating the last line when the text does not fit. Blank text and
+// widths nothing fits in give a single empty line.
+func Wrap(text string, font Font, width, maxLines int) []string {
+	if maxLines <= 1 || width <= 0 || strings.TrimSpace(text) == "" {
+		return []string{Fit(text, font, width)}
+	}
+
+	lines, rest := wrap(strings.Fields(text), font, width, maxLines)
+	if len(rest) == 0 {
+		return lines
+	}
+
+	lines, rest = wrap(strings.Fields(Abbreviate(text)), font, width, maxLines)
+	if len(rest) == 0 {
+		return lines
+	}
+
+	last := append(strings.Fields(lines[len(lines)-1]), rest...)
+	lines[len(lines)-1] = truncate(last, font, width)
+	return lines
+}
+
+// Lines lays text out for every supported font
+func Lines(text string, width, maxLines int) map[string][]string {
+	if width <= 0 {
+		width = DisplayWidth
+	}
+	if maxLines <= 0 {
+		maxLines = DefaultMaxLines
+	}
+
+	lines := make(map[string][]string, len(Fonts))
+	for name, font := range Fonts {
+		lines[name] = Wrap(text, font, width, maxLines)
+	}
+	return lines
+}
+
+// wrap greedily fills up to limit lines and returns the words that did not fit
+func wrap(words []string, font Font, width, limit int) (lines []string, rest []string) {
+	var chunks []string
+	for _, word := range words {
+		chunks = append(chunks, split(word, font, width)...)
+	}
+
+	line := ""
+	for i, chunk := range chunks {
+		candidate := chunk
+		if line != "" {
+			candidate = line + " " + chunk
+		}
+		if line == "" || font.Width(candidate) <= width {
+			line = candidate
+			continue
+		}
+
+		lines = append(lines, line)
+		if len(lines) == limit {
+			return lines, chunks[i:]
+		}
+		line = chunk
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// truncate keeps as many whole words as fit alongside the ellipsis
+func truncate(words []string, font Font, width int) string {
+	budget := width - font.Width(Ellipsis)
+	if budget <= 0 {
+		return clip(Ellipsis, font, width)
+	}
+
+	out := ""
+	for _, word := range words {
+		candidate := word
+		if out != "" {
+			candidate = out + " " + word
+		}
+		if font.Width(candidate) > budget {
+			break
+		}
+		out = candidate
+	}
+
+	if out == "" && len(words) > 0 {
+		out = clip(words[0], font, budget)
+	}
+
+	return strings.TrimRightFunc(out, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + Ellipsis
+}
+
+// split hard breaks a word that is wider than a whole line
+func split(word string, font Font, width int) []string {
+	var parts []string
+	for font.Width(word) > width {
+		head := clip(word, font, width)
+		if head == "" {
+			break
+		}
+		parts = append(parts, head)
+		word = word[len(head):]
+	}
+	return append(parts, word)
+}
+
+// clip returns the longest prefix of text that fits in width pixels
+func clip(text string, font Font, width int) string {
+	used := 0
+	for i, r := range text {
+		used += font.RuneWidth(r)
+		if used > width {
+			return text[:i]
+		}
+	}
+	return text
+}
@@ -0,0 +1,81 @@
+package layout
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		maxLines int
+		want     []string
+	}{
+		{name: "fits", text: "Standup", width: 64, maxLines: 2, want: []string{"Standup"}},
+		{name: "collapses spaces", text: "  Team \t sync ", width: 64, maxLines: 2, want: []string{"Team sync"}},
+		{name: "wraps words", text: "Quarterly business review", width: 64, maxLines: 2, want: []string{"Quarterly", "business review"}},
+		{name: "abbreviates", text: "Weekly engineering planning meeting with the leadership team", width: 64, maxLines: 2,
+			want: []string{"Wkly eng plng mtg", "with the ldrshp..."}},
+		{name: "truncates one line", text: "A very long meeting name that goes on", width: 64, maxLines: 1, want: []string{"A very long mtg..."}},
+		{name: "zero lines is one line", text: "Team sync", width: 64, maxLines: 0, want: []string{"Team sync"}},
+		{name: "splits long words", text: "Supercalifragilisticexpialidocious", width: 64, maxLines: 2,
+			want: []string{"Supercalifragilist", "icexpialidocious"}},
+		{name: "split word keeps next word", text: "Supercalifragilisticexpialidocious words", width: 64, maxLines: 3,
+			want: []string{"Supercalifragilist", "icexpialidocious", "words"}},
+		{name: "narrow", text: "Lunch", width: 8, maxLines: 2, want: []string{"Lu", "..."}},
+		{name: "empty", text: "", width: 64, maxLines: 2, want: []string{""}},
+		{name: "blank", text: " \t ", width: 64, maxLines: 2, want: []string{""}},
+		{name: "zero width", text: "Standup", width: 0, maxLines: 2, want: []string{""}},
+		{name: "negative width", text: "Standup", width: -5, maxLines: 1, want: []string{""}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Wrap(test.text, TomThumb, test.width, test.maxLines)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Wrap(%q, %d, %d) = %q, want %q", test.text, test.width, test.maxLines, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWrapFits(t *testing.T) {
+	texts := []string{
+		"Quarterly business review with the international marketing department",
+		"Supercalifragilisticexpialidocious antidisestablishmentarianism",
+		"Mmmm WWWW mmmm WWWW mmmm WWWW mmmm WWWW",
+	}
+	for name, font := range Fonts {
+		for _, text := range texts {
+			for _, maxLines := range []int{1, 2, 3} {
+				lines := Wrap(text, font, DisplayWidth, maxLines)
+				if len(lines) > maxLines {
+					t.Errorf("%s: Wrap(%q) has %d lines, want at most %d", name, text, len(lines), maxLines)
+				}
+				for _, line := range lines {
+					if width := font.Width(line); width > DisplayWidth {
+						t.Errorf("%s: line %q is %d pixels wide", name, line, width)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAbbreviate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Weekly Meeting", want: "Wkly Mtg"},
+		{text: "weekly meeting", want: "wkly mtg"},
+		{text: "Planning, review and training.", want: "Plng, rvw & trng."},
+		{text: "Standup", want: "Standup"},
+	}
+	for _, test := range tests {
+		if got := Abbreviate(test.text); got != test.want {
+			t.Errorf("Abbreviate(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Networks is a list of CIDR ranges
+type Networks []*net.IPNet
+
+// ParseNetworks parses CIDR ranges, a bare IP is taken as a single address
+func ParseNetworks(cidrs []string) (Networks, error) {
+	networks := make(Networks, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Contains reports whether ip is in any of the networks
+func (n Networks) Contains(ip net.IP) bool {
+	for _, network := range n {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client behind a chain of trusted proxies.
+// X-Forwarded-For is only read when the peer is a trusted proxy, and it is
+// walked from the right so addresses a client made up are never used: the
+// client is the first address not belonging to a trusted proxy.
+func (n Networks) ClientIP(remote net.IP, forwardedFor string) net.IP {
+	if !n.Contains(remote) || forwardedFor == "" {
+		return remote
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		client = ip
+		if !n.Contains(ip) {
+			break
+		}
+	}
+	return client
+}
@@ -0,0 +1,107 @@
+package openapi
+
+import "reflect"
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem maps lower case HTTP methods to their operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	OperationID string              `json:"operationId,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// NewDocument creates a document that collects its schemas from the generator
+func NewDocument(info Info, g *Generator) *Document {
+	return &Document{
+		OpenAPI:    "3.0.3",
+		Info:       info,
+		Paths:      map[string]PathItem{},
+		Components: Components{Schemas: g.Components},
+	}
+}
+
+// Add registers an operation for a path and method
+func (d *Document) Add(path, method string, op *Operation) {
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][method] = op
+}
+
+// JSON describes a JSON request or response body of the given type
+func (g *Generator) JSON(typ reflect.Type) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: g.Schema(typ)}}
+}
+
+// QueryParameters describes the scalar fields of a struct as query parameters,
+// named by their query tags. Nested fields are left to the request body schema.
+func (g *Generator) QueryParameters(typ reflect.Type) []Parameter {
+	var params []Parameter
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := field.Tag.Get("query")
+		if name == "" || !field.IsExported() {
+			continue
+		}
+
+		schema := g.Schema(field.Type)
+		if schema.Type == "array" || schema.Type == "object" || schema.Ref != "" {
+			continue
+		}
+		applyTags(schema, field.Tag)
+
+		params = append(params, Parameter{
+			Name:        name,
+			In:          "query",
+			Description: schema.Description,
+			Required:    field.Tag.Get("required") == "true",
+			Schema:      schema,
+		})
+	}
+	return params
+}
@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Schema is the subset of the OpenAPI 3 schema object the generator emits
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Generator builds schemas from Go types, collecting named structs as components.
+//
+// Field constraints are read from struct tags, using the same `required:"true"`
+// convention as pkg/config:
+//
+//	Width int `json:"width" minimum:"1" maximum:"128" doc:"Display width in pixels"`
+//
+// Supported tags are doc, required, format, pattern, enum (space separated),
+// minimum, maximum and maxLength.
+type Generator struct {
+	Components map[string]*Schema
+}
+
+// NewGenerator creates a Generator with no components
+func NewGenerator() *Generator {
+	return &Generator{Components: map[string]*Schema{}}
+}
+
+// Schema returns the schema of a type, a $ref for named structs
+func (g *Generator) Schema(typ reflect.Type) *Schema {
+	nullable := false
+	for typ.Kind() == reflect.Ptr {
+		typ, nullable = typ.Elem(), true
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		format := ""
+		if typ.Kind() == reflect.Int64 || typ.Kind() == reflect.Uint64 {
+			format = "int64"
+		}
+		return &Schema{Type: "integer", Format: format, Nullable: nullable}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Nullable: nullable}
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.Schema(typ.Elem()), Nullable: true}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(typ.Elem()), Nullable: true}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.object(typ)
+		}
+		name := ComponentName(typ)
+		if _, ok := g.Components[name]; !ok {
+			g.Components[name] = &Schema{}
+			*g.Components[name] = *g.object(typ)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name, Nullable: nullable}
+	}
+
+	return &Schema{}
+}
+
+// object builds the schema of a struct from its exported fields
+func (g *Generator) object(typ reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, ok := FieldName(field)
+		if !ok {
+			continue
+		}
+
+		property := g.Schema(field.Type)
+		if property.Ref != "" {
+			// siblings of $ref are ignored, so wrap it to keep the description
+			property = &Schema{
+				AllOf:       []*Schema{{Ref: property.Ref}},
+				Description: field.Tag.Get("doc"),
+				Nullable:    property.Nullable,
+			}
+		} else {
+			applyTags(property, field.Tag)
+		}
+
+		if field.Tag.Get("required") == "true" {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+
+	return schema
+}
+
+// FieldName returns the JSON name of a struct field and whether it is serialized
+func FieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+// ComponentName names a struct type in the components section. Generic
+// instances such as BaseResponse[types.Event] become BaseResponseOfEvent and
+// BaseResponse[[]types.Event] BaseResponseOfListOfEvent. Types of versioned
+// packages such as api/v2 are prefixed with their version, e.g. V2Event.
+func ComponentName(typ reflect.Type) string {
+	name, args, ok := strings.Cut(typ.Name(), "[")
+	name = versionPrefix(typ.PkgPath()) + name
+	if !ok {
+		return name
+	}
+
+	args = strings.TrimSuffix(args, "]")
+	list := ""
+	for strings.HasPrefix(args, "[]") {
+		list, args = list+"ListOf", strings.TrimPrefix(args, "[]")
+	}
+	pkg := ""
+	if i := strings.LastIndex(args, "."); i >= 0 {
+		pkg, args = args[:i], args[i+1:]
+	}
+	return name + "Of" + list + versionPrefix(pkg) + args
+}
+
+// versionPrefix returns V2 for a package path ending in /v2 and nothing for
+// packages that are not versioned
+func versionPrefix(pkgPath string) string {
+	version := path.Base(pkgPath)
+	if len(version) < 2 || version[0] != 'v' {
+		return ""
+	}
+	if _, err := strconv.Atoi(version[1:]); err != nil {
+		return ""
+	}
+	return "V" + version[1:]
+}
+
+// applyTags copies the constraints declared in struct tags onto a schema
+func applyTags(schema *Schema, tag reflect.StructTag) {
+	schema.Description = tag.Get("doc")
+	if format := tag.Get("format"); format != "" {
+		schema.Format = format
+	}
+	schema.Pattern = tag.Get("pattern")
+	if enum := tag.Get("enum"); enum != "" {
+		schema.Enum = strings.Fields(enum)
+	}
+	if v, err := strconv.ParseFloat(tag.Get("minimum"), 64); err == nil {
+		schema.Minimum = &v
+	}
+	if v, err := strconv.ParseFloat(tag.Get("maximum"), 64); err == nil {
+		schema.Maximum = &v
+	}
+	if v, err := strconv.Atoi(tag.Get("maxLength")); err == nil {
+		schema.MaxLength = &v
+	}
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/sliceutil"
+)
+
+// FieldError is a schema violation of a single field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FormatChecker validates strings that declare a format
+type FormatChecker func(value string) error
+
+// Validator checks Go values against the schemas generated for their types.
+// Zero values of optional fields are treated as absent and are not checked.
+type Validator struct {
+	Generator *Generator
+	Formats   map[string]FormatChecker
+
+	patterns sync.Map
+}
+
+// Validate returns every violation found in value
+func (v *Validator) Validate(value interface{}) []FieldError {
+	rv := reflect.ValueOf(value)
+	return v.validate("", rv, v.Generator.Schema(rv.Type()))
+}
+
+func (v *Validator) validate(path string, rv reflect.Value, schema *Schema) []FieldError {
+	schema = v.resolve(schema)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		return v.validateStruct(path, rv, schema)
+	case reflect.Slice, reflect.Array:
+		var errs []FieldError
+		for i := 0; i < rv.Len(); i++ {
+			errs = append(errs, v.validate(fmt.Sprintf("%s[%d]", path, i), rv.Index(i), schema.Items)...)
+		}
+		return errs
+	case reflect.String:
+		return v.validateString(path, rv.String(), schema)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return validateNumber(path, float64(rv.Int()), schema)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return validateNumber(path, float64(rv.Uint()), schema)
+	case reflect.Float32, reflect.Float64:
+		return validateNumber(path, rv.Float(), schema)
+	}
+	return nil
+}
+
+func (v *Validator) validateStruct(path string, rv reflect.Value, schema *Schema) []FieldError {
+	var errs []FieldError
+
+	for i := 0; i < rv.NumField(); i++ {
+		name, ok := FieldName(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		field := rv.Field(i)
+		if field.IsZero() {
+			for _, required := range schema.Required {
+				if required == name {
+					errs = append(errs, FieldError{Field: fieldPath, Message: "is required"})
+				}
+			}
+			continue
+		}
+
+		if property, ok := schema.Properties[name]; ok {
+			errs = append(errs, v.validate(fieldPath, field, property)...)
+		}
+	}
+
+	return errs
+}
+
+func (v *Validator) validateString(path, value string, schema *Schema) []FieldError {
+	var errs []FieldError
+
+	if schema.MaxLength != nil && utf8.RuneCountInString(value) > *schema.MaxLength {
+		errs = append(errs, FieldError{Field: path, Message: fmt.Sprintf("must be at most %d characters", *schema.MaxLength)})
+	}
+	if len(schema.Enum) > 0 && !sliceutil.ContainsString(schema.Enum, value) {
+		errs = append(errs, FieldError{Field: path, Message: "must be one of " + strings.Join(schema.Enum, ", ")})
+	}
+	if schema.Pattern != "" && !v.pattern(schema.Pattern).MatchString(value) {
+		errs = append(errs, FieldError{Field: path, Message: "must match " + schema.Pattern})
+	}
+	if check, ok := v.Formats[schema.Format]; ok {
+		if err := check(value); err != nil {
+			errs = append(errs, FieldError{Field: path, Message: err.Error()})
+		}
+	}
+
+	return errs
+}
+
+func validateNumber(path string, value float64, schema *Schema) []FieldError {
+	if schema.Minimum != nil && value < *schema.Minimum {
+		return []FieldError{{Field: path, Message: fmt.Sprintf("must be at least %v", *schema.Minimum)}}
+	}
+	if schema.Maximum != nil && value > *schema.Maximum {
+		return []FieldError{{Field: path, Message: fmt.Sprintf("must be at most %v", *schema.Maximum)}}
+	}
+	return nil
+}
+
+// resolve follows $ref and allOf wrappers to the schema that holds the constraints
+func (v *Validator) resolve(schema *Schema) *Schema {
+	for schema != nil {
+		switch {
+		case schema.Ref != "":
+			schema = v.Generator.Components[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		case len(schema.AllOf) == 1:
+			schema = schema.AllOf[0]
+		default:
+			return schema
+		}
+	}
+	return &Schema{}
+}
+
+func (v *Validator) pattern(expr string) *regexp.Regexp {
+	if re, ok := v.patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	v.patterns.Store(expr, re)
+	return re
+}
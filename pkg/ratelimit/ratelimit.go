@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/netutil"
+)
+
+// sweepInterval is how often windows that ended are dropped
+const sweepInterval = time.Minute
+
+// Limit allows Max requests per window of WindowSeconds
+type Limit struct {
+	Max           int
+	WindowSeconds int
+}
+
+// Window returns the length of the limit's window
+func (l Limit) Window() time.Duration {
+	return time.Duration(l.WindowSeconds) * time.Second
+}
+
+// Tiers are the limits of anonymous clients, keyed by IP, and of
+// authenticated clients, keyed by the subject of their credentials
+type Tiers struct {
+	Anonymous     Limit
+	Authenticated Limit
+}
+
+// Route overrides the tiers of a route, zero limits fall back to the defaults
+type Route struct {
+	Path          string
+	Anonymous     Limit
+	Authenticated Limit
+}
+
+// Config are the limits of the server
+type Config struct {
+	// Exempt are the CIDR ranges that are never limited
+	Exempt        []string `cidr:"true"`
+	Anonymous     Limit
+	Authenticated Limit
+	Routes        []Route
+}
+
+// For returns the tiers of a route and whether the route has its own limits
+func (c Config) For(path string) (Tiers, bool) {
+	tiers := Tiers{Anonymous: c.Anonymous, Authenticated: c.Authenticated}
+	for _, route := range c.Routes {
+		if route.Path != path {
+			continue
+		}
+		if route.Anonymous.Max > 0 {
+			tiers.Anonymous = route.Anonymous
+		}
+		if route.Authenticated.Max > 0 {
+			tiers.Authenticated = route.Authenticated
+		}
+		return tiers, true
+	}
+	return tiers, false
+}
+
+// Result is the state of a key's window after a request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the window ends
+	Reset time.Duration
+}
+
+// Limiter counts requests per key in fixed windows
+type Limiter struct {
+	limits atomic.Pointer[limits]
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	nextSweep time.Time
+}
+
+// limits are the parsed config of a limiter
+type limits struct {
+	config Config
+	exempt netutil.Networks
+}
+
+type window struct {
+	count int
+	ends  time.Time
+}
+
+// New creates a limiter with no requests counted
+func New(config Config) (*Limiter, error) {
+	l := &Limiter{windows: map[string]*window{}}
+	if err := l.Reconfigure(config); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reconfigure replaces the limits, requests already counted are kept and
+// count against the new limits
+func (l *Limiter) Reconfigure(config Config) error {
+	exempt, err := netutil.ParseNetworks(config.Exempt)
+	if err != nil {
+		return err
+	}
+	l.limits.Store(&limits{config: config, exempt: exempt})
+	return nil
+}
+
+// For returns the tiers of a route and whether the route has its own limits
+func (l *Limiter) For(path string) (Tiers, bool) {
+	return l.limits.Load().config.For(path)
+}
+
+// Exempt reports whether requests of ip are never limited
+func (l *Limiter) Exempt(ip net.IP) bool {
+	return l.limits.Load().exempt.Contains(ip)
+}
+
+// Allow counts a request of key against limit
+func (l *Limiter) Allow(key string, limit Limit, now time.Time) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.ends) {
+		w = &window{ends: now.Add(limit.Window())}
+		l.windows[key] = w
+	}
+
+	result := Result{Limit: limit.Max, Reset: w.ends.Sub(now)}
+	if w.count >= limit.Max {
+		return result
+	}
+	w.count++
+	result.Allowed = true
+	result.Remaining = limit.Max - w.count
+	return result
+}
+
+// Peek reports whether a request of key would be allowed by limit, without
+// counting it
+func (l *Limiter) Peek(key string, limit Limit, now time.Time) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count, ends := 0, now.Add(limit.Window())
+	if w, ok := l.windows[key]; ok && now.Before(w.ends) {
+		count, ends = w.count, w.ends
+	}
+	return Result{
+		Allowed:   count < limit.Max,
+		Limit:     limit.Max,
+		Remaining: max(limit.Max-count, 0),
+		Reset:     ends.Sub(now),
+	}
+}
+
+// sweep drops the windows that ended, the caller holds the lock
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	for key, w := range l.windows {
+		if !now.Before(w.ends) {
+			delete(l.windows, key)
+		}
+	}
+	l.nextSweep = now.Add(sweepInterval)
+}
@@ -0,0 +1,55 @@
+package redact
+
+import (
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// core masks the URLs of every message, string field and error before they
+// reach the wrapped core. Fields named like URLs are masked as a whole, which
+// also covers request paths whose query strings carry feed URLs.
+type core struct {
+	zapcore.Core
+}
+
+// WrapCore adds redaction to a logger, use it with zap.WrapCore
+func WrapCore(c zapcore.Core) zapcore.Core {
+	return core{c}
+}
+
+func (c core) With(fields []zapcore.Field) zapcore.Core {
+	return core{c.Core.With(scrub(fields))}
+}
+
+func (c core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = Text(entry.Message)
+	return c.Core.Write(entry, scrub(fields))
+}
+
+func scrub(fields []zapcore.Field) []zapcore.Field {
+	scrubbed := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			if strings.HasSuffix(strings.ToLower(field.Key), "url") {
+				field.String = URL(field.String)
+			} else {
+				field.String = Text(field.String)
+			}
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok {
+				field = zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: Text(err.Error())}
+			}
+		}
+		scrubbed[i] = field
+	}
+	return scrubbed
+}
@@ -0,0 +1,104 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Mask replaces secrets
+	Mask = "REDACTED"
+	// minTokenLength is the shortest path segment that may be a random token
+	minTokenLength = 20
+)
+
+// urlPattern finds URLs in free text such as error messages
+var urlPattern = regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://[^\s"'<>]+`)
+
+// URL masks the secrets of a feed URL: user info, query values, Google
+// private-xxx segments and path segments that look like random tokens. The
+// fragment is dropped. Relative URLs such as request paths work as well.
+func URL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Mask
+	}
+
+	if u.User != nil {
+		u.User = url.User(Mask)
+	}
+	u.Fragment, u.RawFragment = "", ""
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, "private-"):
+			segments[i] = "private-" + Mask
+		case looksLikeToken(segment):
+			segments[i] = Mask
+		}
+	}
+	u.RawPath = strings.Join(segments, "/")
+	if path, err := url.PathUnescape(u.RawPath); err == nil {
+		u.Path = path
+	}
+
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			params[i] = key + "=" + Mask
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	return u.String()
+}
+
+// URLs masks every URL of a list
+func URLs(raw []string) []string {
+	masked := make([]string, len(raw))
+	for i, u := range raw {
+		masked[i] = URL(u)
+	}
+	return masked
+}
+
+// Text masks the URLs found in free text
+func Text(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	return urlPattern.ReplaceAllStringFunc(s, URL)
+}
+
+// Summary describes a body by its size and hash, so logs can tell feeds apart
+// without holding their contents
+func Summary(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return fmt.Sprintf("%d bytes, sha256 %s", len(body), hex.EncodeToString(sum[:8]))
+}
+
+// looksLikeToken reports whether a path segment is long and mixes letters and
+// digits, as random tokens do and file names rarely do
+func looksLikeToken(segment string) bool {
+	if len(segment) < minTokenLength {
+		return false
+	}
+
+	var letters, digits bool
+	for _, r := range segment {
+		switch {
+		case unicode.IsLetter(r):
+			letters = true
+		case unicode.IsDigit(r):
+			digits = true
+		}
+	}
+	return letters && digits
+}
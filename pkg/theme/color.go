@@ -0,0 +1,108 @@
+package theme
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RGB is a 24 bit color
+type RGB struct {
+	R, G, B uint8
+}
+
+// names holds the CSS3 color names RFC 7986 calendars commonly use in COLOR
+var names = map[string]RGB{
+	"black":     {0x00, 0x00, 0x00},
+	"white":     {0xFF, 0xFF, 0xFF},
+	"red":       {0xFF, 0x00, 0x00},
+	"crimson":   {0xDC, 0x14, 0x3C},
+	"orange":    {0xFF, 0xA5, 0x00},
+	"gold":      {0xFF, 0xD7, 0x00},
+	"yellow":    {0xFF, 0xFF, 0x00},
+	"lime":      {0x00, 0xFF, 0x00},
+	"green":     {0x00, 0x80, 0x00},
+	"olive":     {0x80, 0x80, 0x00},
+	"teal":      {0x00, 0x80, 0x80},
+	"turquoise": {0x40, 0xE0, 0xD0},
+	"cyan":      {0x00, 0xFF, 0xFF},
+	"aqua":      {0x00, 0xFF, 0xFF},
+	"blue":      {0x00, 0x00, 0xFF},
+	"navy":      {0x00, 0x00, 0x80},
+	"royalblue": {0x41, 0x69, 0xE1},
+	"skyblue":   {0x87, 0xCE, 0xEB},
+	"purple":    {0x80, 0x00, 0x80},
+	"violet":    {0xEE, 0x82, 0xEE},
+	"magenta":   {0xFF, 0x00, 0xFF},
+	"fuchsia":   {0xFF, 0x00, 0xFF},
+	"pink":      {0xFF, 0xC0, 0xCB},
+	"hotpink":   {0xFF, 0x69, 0xB4},
+	"brown":     {0xA5, 0x2A, 0x2A},
+	"maroon":    {0x80, 0x00, 0x00},
+	"salmon":    {0xFA, 0x80, 0x72},
+	"coral":     {0xFF, 0x7F, 0x50},
+	"tomato":    {0xFF, 0x63, 0x47},
+	"gray":      {0x80, 0x80, 0x80},
+	"grey":      {0x80, 0x80, 0x80},
+	"silver":    {0xC0, 0xC0, 0xC0},
+}
+
+// Parse reads a hex color (#RGB, #RRGGBB or Apple's #RRGGBBAA) or a CSS3 color name
+func Parse(s string) (RGB, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := names[s]; ok {
+		return c, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	switch len(hex) {
+	case 3:
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	case 8:
+		hex = hex[:6]
+	}
+	if len(hex) != 6 {
+		return RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+	return RGB{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// Hex formats the color as #RRGGBB
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// Luminance returns the relative luminance as defined by WCAG 2
+func (c RGB) Luminance() float64 {
+	channel := func(v uint8) float64 {
+		f := float64(v) / 255
+		if f <= 0.03928 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// Contrast returns the WCAG contrast ratio between two colors
+func Contrast(a, b RGB) float64 {
+	la, lb := a.Luminance(), b.Luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// mix blends c towards other by the given amount between 0 and 1
+func (c RGB) mix(other RGB, amount float64) RGB {
+	blend := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*amount))
+	}
+	return RGB{blend(c.R, other.R), blend(c.G, other.G), blend(c.B, other.B)}
+}
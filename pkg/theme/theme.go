@@ -0,0 +1,67 @@
+package theme
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+const (
+	// MinContrast is the contrast ratio every palette guarantees between its
+	// foreground and background
+	MinContrast = 4.5
+	// maxBackgroundLuminance keeps backgrounds dim, a brightly lit background
+	// washes out the text on an LED matrix
+	maxBackgroundLuminance = 0.04
+)
+
+var (
+	Black = RGB{0x00, 0x00, 0x00}
+	White = RGB{0xFF, 0xFF, 0xFF}
+)
+
+// categoryColors are saturated colors that stay distinct on the LED matrix
+var categoryColors = []RGB{
+	{0x00, 0x9F, 0xFF}, // blue
+	{0x00, 0xD0, 0x60}, // green
+	{0xFF, 0x8C, 0x00}, // orange
+	{0xC0, 0x40, 0xFF}, // purple
+	{0xFF, 0x30, 0x60}, // red
+	{0x00, 0xD0, 0xD0}, // cyan
+	{0xFF, 0xD0, 0x00}, // yellow
+	{0xFF, 0x60, 0xC0}, // pink
+}
+
+// Palette is a foreground and background pair
+type Palette struct {
+	Foreground RGB
+	Background RGB
+}
+
+// Default is used for events that have no color from any source
+var Default = Palette{Foreground: White, Background: Black}
+
+// ForCategory picks a stable palette color for a category name
+func ForCategory(name string) RGB {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(strings.TrimSpace(name))))
+	return categoryColors[h.Sum32()%uint32(len(categoryColors))]
+}
+
+// Readable derives a palette from a base color: a dim background tinted with the
+// color and a foreground lightened until it reaches MinContrast against it
+func Readable(base RGB) Palette {
+	bg := base
+	for bg.Luminance() > maxBackgroundLuminance {
+		bg = bg.mix(Black, 0.1)
+	}
+
+	fg := base
+	for amount := 0.1; Contrast(fg, bg) < MinContrast && amount <= 1; amount += 0.1 {
+		fg = base.mix(White, amount)
+	}
+	if Contrast(fg, bg) < MinContrast {
+		fg = White
+	}
+
+	return Palette{Foreground: fg, Background: bg}
+}
@@ -0,0 +1,103 @@
+package theme
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  RGB
+		err   bool
+	}{
+		{value: "#FF8800", want: RGB{0xFF, 0x88, 0x00}},
+		{value: "#f80", want: RGB{0xFF, 0x88, 0x00}},
+		{value: "#FF8800CC", want: RGB{0xFF, 0x88, 0x00}},
+		{value: "ff8800", want: RGB{0xFF, 0x88, 0x00}},
+		{value: " RoyalBlue ", want: RGB{0x41, 0x69, 0xE1}},
+		{value: "", err: true},
+		{value: "#FF88", err: true},
+		{value: "#GG8800", err: true},
+		{value: "chartreuse", err: true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.value)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("Parse(%q) = %v, %v, want %v (error %v)", test.value, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestReadable(t *testing.T) {
+	bases := append([]RGB{Black, White}, categoryColors...)
+	for _, named := range names {
+		bases = append(bases, named)
+	}
+	for r := 0; r <= 255; r += 15 {
+		for g := 0; g <= 255; g += 15 {
+			for b := 0; b <= 255; b += 15 {
+				bases = append(bases, RGB{uint8(r), uint8(g), uint8(b)})
+			}
+		}
+	}
+
+	for _, base := range bases {
+		palette := Readable(base)
+		if contrast := Contrast(palette.Foreground, palette.Background); contrast < MinContrast {
+			t.Errorf("Readable(%s) = %s on %s, contrast %.2f is below %v",
+				base.Hex(), palette.Foreground.Hex(), palette.Background.Hex(), contrast, MinContrast)
+		}
+		if lum := palette.Background.Luminance(); lum > maxBackgroundLuminance {
+			t.Errorf("Readable(%s) background %s has luminance %.3f", base.Hex(), palette.Background.Hex(), lum)
+		}
+	}
+}
+
+func TestReadableKeepsHue(t *testing.T) {
+	tests := []struct {
+		name string
+		base RGB
+	}{
+		{name: "red", base: RGB{0xFF, 0x00, 0x00}},
+		{name: "green", base: RGB{0x00, 0x80, 0x00}},
+		{name: "blue", base: RGB{0x00, 0x00, 0xFF}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			palette := Readable(test.base)
+			if dominant(palette.Background) != dominant(test.base) {
+				t.Errorf("background %s is not tinted %s", palette.Background.Hex(), test.name)
+			}
+			if palette.Foreground == White {
+				t.Errorf("foreground fell back to white instead of a tint of %s", test.name)
+			}
+		})
+	}
+}
+
+// dominant returns the index of the strongest channel
+func dominant(c RGB) int {
+	switch {
+	case c.R >= c.G && c.R >= c.B:
+		return 0
+	case c.G >= c.B:
+		return 1
+	}
+	return 2
+}
+
+func TestContrast(t *testing.T) {
+	if got := Contrast(Black, White); got < 20.9 || got > 21.1 {
+		t.Errorf("Contrast(black, white) = %v, want 21", got)
+	}
+	if got := Contrast(White, Black); got != Contrast(Black, White) {
+		t.Errorf("Contrast is not symmetric: %v", got)
+	}
+	if got := Contrast(Default.Foreground, Default.Background); got < MinContrast {
+		t.Errorf("default palette contrast %v is below %v", got, MinContrast)
+	}
+}
+
+func TestForCategory(t *testing.T) {
+	if ForCategory("Work") != ForCategory(" work ") {
+		t.Error("category colors depend on case or spacing")
+	}
+}
@@ -0,0 +1,176 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config describes how the server serves TLS
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// MinVersion is 1.2 or 1.3, 1.2 when empty
+	MinVersion string `oneof:"1.2 1.3"`
+	// CipherSuites are the TLS 1.2 suites to offer by their Go names, e.g.
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. The secure defaults are used when
+	// empty. TLS 1.3 suites are not configurable.
+	CipherSuites []string
+	// ClientCA is a PEM bundle of the CAs client certificates are verified
+	// against, client certificates are not asked for when empty
+	ClientCA string
+	// RequireClientCert rejects connections without a verified client
+	// certificate, otherwise they are optional
+	RequireClientCert bool
+}
+
+// Enabled reports whether TLS is configured, New reports a client CA without
+// a certificate
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.ClientCA != ""
+}
+
+var versions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// New builds the TLS config of the server. The certificate is read again
+// whenever its files change on disk, so renewals need no restart.
+func New(c Config) (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("both a certificate and a key file are required")
+	}
+
+	minVersion, ok := versions[c.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported minimum TLS version %q, expected 1.2 or 1.3", c.MinVersion)
+	}
+	suites, err := cipherSuites(c.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := &reloader{certFile: c.CertFile, keyFile: c.KeyFile}
+	if err := certs.reload(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   suites,
+		GetCertificate: certs.certificate,
+	}
+
+	if c.ClientCA != "" {
+		pem, err := os.ReadFile(c.ClientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", c.ClientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if c.RequireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return config, nil
+}
+
+// cipherSuites looks up suites by name, only suites without known weaknesses
+// are accepted
+func cipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	secure := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// checkInterval limits how often the certificate files are looked at
+const checkInterval = 10 * time.Second
+
+// reloader serves a certificate and reads it again when its files change. A
+// failed reload keeps the current certificate, e.g. while a renewal has only
+// written one of the files.
+type reloader struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modified  time.Time
+	nextCheck time.Time
+}
+
+func (r *reloader) certificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); now.After(r.nextCheck) {
+		r.nextCheck = now.Add(checkInterval)
+		_ = r.load()
+	}
+	return r.cert, nil
+}
+
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.load()
+}
+
+// load reads the certificate when a file changed, the caller holds the lock
+func (r *reloader) load() error {
+	modified, err := latest(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && modified.Equal(r.modified) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert, r.modified = &cert, modified
+	return nil
+}
+
+// latest returns the most recent modification time of files
+func latest(files ...string) (time.Time, error) {
+	var modified time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	return modified, nil
+}
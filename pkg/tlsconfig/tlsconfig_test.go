@@ -0,0 +1,302 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issuer is a certificate and its key, able to sign others when it is a CA
+type issuer struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+var serial int64
+
+func newCert(t *testing.T, name string, parent *issuer, ca bool) *issuer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &issuer{cert: cert, der: der, key: key}
+}
+
+// write writes the certificate and key as PEM files into dir
+func (i *issuer) write(t *testing.T, dir, name string) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(i.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (i *issuer) tlsCert() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{i.der}, PrivateKey: i.key}
+}
+
+func TestCipherSuites(t *testing.T) {
+	tests := []struct {
+		name  string
+		suite string
+		ok    bool
+	}{
+		{name: "secure", suite: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", ok: true},
+		{name: "chacha", suite: "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256", ok: true},
+		{name: "rc4", suite: "TLS_ECDHE_RSA_WITH_RC4_128_SHA"},
+		{name: "3des", suite: "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA"},
+		{name: "cbc sha256", suite: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256"},
+		{name: "unknown", suite: "TLS_MADE_UP"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids, err := cipherSuites([]string{test.suite})
+			if test.ok != (err == nil) {
+				t.Fatalf("cipherSuites(%s) = %v, %v, want ok %v", test.suite, ids, err, test.ok)
+			}
+			if !test.ok && !strings.Contains(err.Error(), test.suite) {
+				t.Errorf("error %v does not name the suite", err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := newCert(t, "server", nil, false).write(t, dir, "server")
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		err    string
+	}{
+		{name: "defaults", config: Config{CertFile: certFile, KeyFile: keyFile}},
+		{name: "no key", config: Config{CertFile: certFile}, err: "both a certificate and a key file"},
+		{name: "client CA only", config: Config{ClientCA: certFile}, err: "both a certificate and a key file"},
+		{name: "TLS 1.1", config: Config{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.1"}, err: "unsupported minimum TLS version"},
+		{name: "insecure suite", config: Config{CertFile: certFile, KeyFile: keyFile, CipherSuites: []string{"TLS_ECDHE_RSA_WITH_RC4_128_SHA"}}, err: "insecure cipher suite"},
+		{name: "CA without certificates", config: Config{CertFile: certFile, KeyFile: keyFile, ClientCA: notPEM}, err: "no certificates found"},
+		{name: "missing certificate", config: Config{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile}, err: "no such file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := New(test.config)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("New error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.MinVersion != tls.VersionTLS12 || config.ClientAuth != tls.NoClientCert {
+				t.Errorf("New = min version %x, client auth %v, want TLS 1.2 without client certificates", config.MinVersion, config.ClientAuth)
+			}
+		})
+	}
+}
+
+// handshake connects to a server with config, presenting clientCert when it
+// is set, and returns the verified chains the server saw
+func handshake(t *testing.T, config *tls.Config, roots *x509.CertPool, clientCert *issuer) ([][]*x509.Certificate, error) {
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type result struct {
+		chains [][]*x509.Certificate
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer conn.Close()
+		tlsConn := conn.(*tls.Conn)
+		err = tlsConn.Handshake()
+		done <- result{chains: tlsConn.ConnectionState().VerifiedChains, err: err}
+	}()
+
+	clientConfig := &tls.Config{RootCAs: roots}
+	if clientCert != nil {
+		// Sent even when the server does not list its CA, as a hostile
+		// client would
+		cert := clientCert.tlsCert()
+		clientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &cert, nil
+		}
+	}
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientConfig)
+	if err == nil {
+		// TLS 1.3 servers reject client certificates after the client
+		// finished, a read surfaces the alert
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	r := <-done
+	return r.chains, r.err
+}
+
+func TestClientCAModes(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "Test CA", nil, true)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newCert(t, "server", ca, false).write(t, dir, "server")
+	client := newCert(t, "tidbyt", ca, false)
+	stranger := newCert(t, "stranger", newCert(t, "Other CA", nil, true), false)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name     string
+		config   Config
+		cert     *issuer
+		ok       bool
+		verified bool
+	}{
+		{name: "no CA, no certificate", config: Config{}, ok: true},
+		{name: "no CA ignores certificates", config: Config{}, cert: client, ok: true},
+		{name: "optional without certificate", config: Config{ClientCA: caFile}, ok: true},
+		{name: "optional with certificate", config: Config{ClientCA: caFile}, cert: client, ok: true, verified: true},
+		{name: "optional with untrusted certificate", config: Config{ClientCA: caFile}, cert: stranger},
+		{name: "required without certificate", config: Config{ClientCA: caFile, RequireClientCert: true}},
+		{name: "required with certificate", config: Config{ClientCA: caFile, RequireClientCert: true}, cert: client, ok: true, verified: true},
+		{name: "required with untrusted certificate", config: Config{ClientCA: caFile, RequireClientCert: true}, cert: stranger},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.CertFile, test.config.KeyFile = certFile, keyFile
+			config, err := New(test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			chains, err := handshake(t, config, roots, test.cert)
+			if test.ok != (err == nil) {
+				t.Fatalf("handshake error = %v, want ok %v", err, test.ok)
+			}
+			if verified := len(chains) > 0; verified != test.verified {
+				t.Errorf("client certificate verified = %v, want %v", verified, test.verified)
+			}
+			if test.verified && chains[0][0].Subject.CommonName != "tidbyt" {
+				t.Errorf("verified certificate %q, want tidbyt", chains[0][0].Subject.CommonName)
+			}
+		})
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	first := newCert(t, "first", nil, false)
+	certFile, keyFile := first.write(t, dir, "server")
+
+	r := &reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+	serving := func() string {
+		cert, err := r.certificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	// touch moves the modification time of the files on and lets the
+	// reloader look at them straight away
+	touch := func(offset time.Duration) {
+		for _, file := range []string{certFile, keyFile} {
+			if err := os.Chtimes(file, time.Now().Add(offset), time.Now().Add(offset)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.nextCheck = time.Time{}
+	}
+
+	// A renewal that has only written the certificate keeps the current one
+	second := newCert(t, "second", nil, false)
+	secondCert, secondKey := second.write(t, t.TempDir(), "server")
+	rename := func(from, to string) {
+		if err := os.Rename(from, to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rename(secondCert, certFile)
+	touch(time.Minute)
+	if got := serving(); got != "first" {
+		t.Errorf("serving %q while the key is missing, want first", got)
+	}
+
+	rename(secondKey, keyFile)
+	touch(2 * time.Minute)
+	if got := serving(); got != "second" {
+		t.Errorf("serving %q after the renewal, want second", got)
+	}
+
+	// Files are only looked at every checkInterval
+	third := newCert(t, "third", nil, false)
+	third.write(t, dir, "server")
+	touch(3 * time.Minute)
+	r.nextCheck = time.Now().Add(checkInterval)
+	if got := serving(); got != "second" {
+		t.Errorf("serving %q before the next check, want second", got)
+	}
+}
@@ -0,0 +1,256 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: calendar/v1/calendar.proto
+
+package calendarv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	CalendarService_NextEvent_FullMethodName      = "/calendar.v1.CalendarService/NextEvent"
+	CalendarService_ListEvents_FullMethodName     = "/calendar.v1.CalendarService/ListEvents"
+	CalendarService_FreeBusy_FullMethodName       = "/calendar.v1.CalendarService/FreeBusy"
+	CalendarService_WatchNextEvent_FullMethodName = "/calendar.v1.CalendarService/WatchNextEvent"
+)
+
+// CalendarServiceClient is the client API for CalendarService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CalendarServiceClient interface {
+	// NextEvent returns the display state for the next event of a feed.
+	NextEvent(ctx context.Context, in *NextEventRequest, opts ...grpc.CallOption) (*NextEventResponse, error)
+	// ListEvents returns every event of a feed in the lookahead window.
+	ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
+	// FreeBusy returns the merged busy periods of a feed in the lookahead window.
+	FreeBusy(ctx context.Context, in *FreeBusyRequest, opts ...grpc.CallOption) (*FreeBusyResponse, error)
+	// WatchNextEvent streams the display state whenever it changes.
+	WatchNextEvent(ctx context.Context, in *WatchNextEventRequest, opts ...grpc.CallOption) (CalendarService_WatchNextEventClient, error)
+}
+
+type calendarServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCalendarServiceClient(cc grpc.ClientConnInterface) CalendarServiceClient {
+	return &calendarServiceClient{cc}
+}
+
+func (c *calendarServiceClient) NextEvent(ctx context.Context, in *NextEventRequest, opts ...grpc.CallOption) (*NextEventResponse, error) {
+	out := new(NextEventResponse)
+	err := c.cc.Invoke(ctx, CalendarService_NextEvent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calendarServiceClient) ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error) {
+	out := new(ListEventsResponse)
+	err := c.cc.Invoke(ctx, CalendarService_ListEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calendarServiceClient) FreeBusy(ctx context.Context, in *FreeBusyRequest, opts ...grpc.CallOption) (*FreeBusyResponse, error) {
+	out := new(FreeBusyResponse)
+	err := c.cc.Invoke(ctx, CalendarService_FreeBusy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calendarServiceClient) WatchNextEvent(ctx context.Context, in *WatchNextEventRequest, opts ...grpc.CallOption) (CalendarService_WatchNextEventClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CalendarService_ServiceDesc.Streams[0], CalendarService_WatchNextEvent_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &calendarServiceWatchNextEventClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CalendarService_WatchNextEventClient interface {
+	Recv() (*WatchNextEventResponse, error)
+	grpc.ClientStream
+}
+
+type calendarServiceWatchNextEventClient struct {
+	grpc.ClientStream
+}
+
+func (x *calendarServiceWatchNextEventClient) Recv() (*WatchNextEventResponse, error) {
+	m := new(WatchNextEventResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CalendarServiceServer is the server API for CalendarService service.
+// All implementations must embed UnimplementedCalendarServiceServer
+// for forward compatibility
+type CalendarServiceServer interface {
+	// NextEvent returns the display state for the next event of a feed.
+	NextEvent(context.Context, *NextEventRequest) (*NextEventResponse, error)
+	// ListEvents returns every event of a feed in the lookahead window.
+	ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
+	// FreeBusy returns the merged busy periods of a feed in the lookahead window.
+	FreeBusy(context.Context, *FreeBusyRequest) (*FreeBusyResponse, error)
+	// WatchNextEvent streams the display state whenever it changes.
+	WatchNextEvent(*WatchNextEventRequest, CalendarService_WatchNextEventServer) error
+	mustEmbedUnimplementedCalendarServiceServer()
+}
+
+// UnimplementedCalendarServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCalendarServiceServer struct {
+}
+
+func (UnimplementedCalendarServiceServer) NextEvent(context.Context, *NextEventRequest) (*NextEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NextEvent not implemented")
+}
+func (UnimplementedCalendarServiceServer) ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEvents not implemented")
+}
+func (UnimplementedCalendarServiceServer) FreeBusy(context.Context, *FreeBusyRequest) (*FreeBusyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FreeBusy not implemented")
+}
+func (UnimplementedCalendarServiceServer) WatchNextEvent(*WatchNextEventRequest, CalendarService_WatchNextEventServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchNextEvent not implemented")
+}
+func (UnimplementedCalendarServiceServer) mustEmbedUnimplementedCalendarServiceServer() {}
+
+// UnsafeCalendarServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CalendarServiceServer will
+// result in compilation errors.
+type UnsafeCalendarServiceServer interface {
+	mustEmbedUnimplementedCalendarServiceServer()
+}
+
+func RegisterCalendarServiceServer(s grpc.ServiceRegistrar, srv CalendarServiceServer) {
+	s.RegisterService(&CalendarService_ServiceDesc, srv)
+}
+
+func _CalendarService_NextEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NextEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalendarServiceServer).NextEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalendarService_NextEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalendarServiceServer).NextEvent(ctx, req.(*NextEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalendarService_ListEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalendarServiceServer).ListEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalendarService_ListEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalendarServiceServer).ListEvents(ctx, req.(*ListEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalendarService_FreeBusy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FreeBusyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalendarServiceServer).FreeBusy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalendarService_FreeBusy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalendarServiceServer).FreeBusy(ctx, req.(*FreeBusyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalendarService_WatchNextEvent_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchNextEventRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CalendarServiceServer).WatchNextEvent(m, &calendarServiceWatchNextEventServer{stream})
+}
+
+type CalendarService_WatchNextEventServer interface {
+	Send(*WatchNextEventResponse) error
+	grpc.ServerStream
+}
+
+type calendarServiceWatchNextEventServer struct {
+	grpc.ServerStream
+}
+
+func (x *calendarServiceWatchNextEventServer) Send(m *WatchNextEventResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// CalendarService_ServiceDesc is the grpc.ServiceDesc for CalendarService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CalendarService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calendar.v1.CalendarService",
+	HandlerType: (*CalendarServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "NextEvent",
+			Handler:    _CalendarService_NextEvent_Handler,
+		},
+		{
+			MethodName: "ListEvents",
+			Handler:    _CalendarService_ListEvents_Handler,
+		},
+		{
+			MethodName: "FreeBusy",
+			Handler:    _CalendarService_FreeBusy_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchNextEvent",
+			Handler:       _CalendarService_WatchNextEvent_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "calendar/v1/calendar.proto",
+}
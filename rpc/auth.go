@@ -0,0 +1,211 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/apikey"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// APIKeyMetadata is the metadata key API keys are sent in
+	APIKeyMetadata = "x-api-key"
+	// RetryAfterMetadata tells clients over their limit how many seconds to wait
+	RetryAfterMetadata = "retry-after"
+)
+
+// Guard authenticates and rate limits the calls of the CalendarService the
+// way the HTTP server guards its calendar routes. With client certificates,
+// JWT or API keys configured, calls need the calendar:read scope.
+type Guard struct {
+	Logger *zap.Logger
+	// ClientCerts authenticates calls made with a verified client
+	// certificate by the identities of its subject
+	ClientCerts bool
+	Identities  []auth.CertIdentity
+	// Keys checks the API keys sent in x-api-key metadata when set
+	Keys *apikey.Store
+	// Verifier checks the bearer tokens sent in authorization metadata when set
+	Verifier *auth.Verifier
+	// Limiter limits calls by subject or by peer address, routes are matched
+	// against full method names, e.g. /calendar.v1.CalendarService/NextEvent
+	Limiter *ratelimit.Limiter
+}
+
+// Unary returns the interceptor guarding unary calls
+func (g *Guard) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := g.check(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the interceptor guarding streaming calls
+func (g *Guard) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := g.check(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &guardedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// guardedStream carries the claims of a call to its handler
+type guardedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *guardedStream) Context() context.Context {
+	return s.ctx
+}
+
+// enabled reports whether calls have to authenticate
+func (g *Guard) enabled() bool {
+	return g.ClientCerts || g.Keys != nil || g.Verifier != nil
+}
+
+// check authenticates a call and counts it against its limit. Calls failing
+// authentication are counted against the anonymous limit of the peer
+// address, and addresses over it are rejected before their credentials are
+// checked again.
+func (g *Guard) check(ctx context.Context, method string) (context.Context, error) {
+	ip := peerIP(ctx)
+	limited := g.Limiter != nil && !g.Limiter.Exempt(net.ParseIP(ip))
+
+	var tiers ratelimit.Tiers
+	bucket := "*"
+	if limited {
+		var own bool
+		if tiers, own = g.Limiter.For(method); own {
+			bucket = method
+		}
+	}
+	ipKey := bucket + "|ip:" + ip
+
+	if g.enabled() {
+		if limited {
+			if result := g.Limiter.Peek(ipKey, tiers.Anonymous, time.Now()); !result.Allowed {
+				return nil, limitReached(ctx, result)
+			}
+		}
+
+		var err error
+		if ctx, err = g.authenticate(ctx); err != nil {
+			if limited {
+				g.Limiter.Allow(ipKey, tiers.Anonymous, time.Now())
+			}
+			return nil, err
+		}
+	}
+
+	if !limited {
+		return ctx, nil
+	}
+	key, limit := ipKey, tiers.Anonymous
+	if claims, ok := auth.FromContext(ctx); ok && claims.Subject != "" {
+		key, limit = bucket+"|sub:"+claims.Subject, tiers.Authenticated
+	}
+	if result := g.Limiter.Allow(key, limit, time.Now()); !result.Allowed {
+		return nil, limitReached(ctx, result)
+	}
+	return ctx, nil
+}
+
+// authenticate puts the claims of the client certificate, API key or bearer
+// token of a call in its context, in that order, and checks they grant the
+// calendar:read scope
+func (g *Guard) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	claims, ok := g.certClaims(ctx)
+	if secret := first(md, APIKeyMetadata); !ok && g.Keys != nil && secret != "" {
+		key, err := g.Keys.Authenticate(secret)
+		if err != nil {
+			g.Logger.Debug("Authenticate", zap.Error(err))
+			return nil, status.Error(codes.Unauthenticated, "the API key is invalid or expired")
+		}
+		claims, ok = key.Claims(), true
+	}
+	if header := first(md, "authorization"); !ok && g.Verifier != nil && header != "" {
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found {
+			return nil, status.Error(codes.Unauthenticated, "a valid bearer token is required")
+		}
+		var err error
+		if claims, err = g.Verifier.Verify(token); err != nil {
+			g.Logger.Debug("Authenticate", zap.Error(err))
+			return nil, status.Error(codes.Unauthenticated, "a valid bearer token is required")
+		}
+		ok = true
+	}
+
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "a bearer token or API key is required")
+	}
+	if !claims.HasScopes(auth.ScopeCalendarRead) {
+		return nil, status.Error(codes.PermissionDenied, "the credentials lack the scopes "+auth.ScopeCalendarRead)
+	}
+	return auth.NewContext(ctx, claims), nil
+}
+
+// certClaims returns the claims of the verified client certificate of a call
+func (g *Guard) certClaims(ctx context.Context) (auth.Claims, bool) {
+	if !g.ClientCerts {
+		return auth.Claims{}, false
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return auth.Claims{}, false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return auth.Claims{}, false
+	}
+	return auth.CertClaims(info.State.VerifiedChains[0][0], g.Identities), true
+}
+
+// limitReached rejects a call over its limit and tells the client when to
+// retry
+func limitReached(ctx context.Context, result ratelimit.Result) error {
+	retry := fmt.Sprint(int(math.Ceil(result.Reset.Seconds())))
+	grpc.SetHeader(ctx, metadata.Pairs(RetryAfterMetadata, retry))
+	return status.Error(codes.ResourceExhausted, "too many requests, retry in "+retry+"s")
+}
+
+// peerIP returns the address of the client of a call
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package rpc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/apikey"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ratelimit"
+	calendarv1 "github.com/quesurifn/ics-calendar-tidbyt-server/proto/calendar/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// guardedClient serves a CalendarServer behind guard
+func guardedClient(t *testing.T, guard *Guard) calendarv1.CalendarServiceClient {
+	return newClient(t,
+		grpc.ChainUnaryInterceptor(guard.Unary()),
+		grpc.ChainStreamInterceptor(guard.Stream()),
+	)
+}
+
+// newVerifier returns a verifier of tokens signed with the returned key
+func newVerifier(t *testing.T) (*auth.Verifier, ed25519.PrivateKey) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "public.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.NewKeySet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &auth.Verifier{Keys: keys, Audience: "ics"}, private
+}
+
+func sign(t *testing.T, key ed25519.PrivateKey, audience, scope string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "device",
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Scope: scope,
+	}).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestGuardAuthentication(t *testing.T) {
+	keys, err := apikey.NewStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reader, err := keys.Create("reader", []string{auth.ScopeCalendarRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, admin, err := keys.Create("admin", []string{auth.ScopeAdmin}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, signer := newVerifier(t)
+
+	client := guardedClient(t, &Guard{Logger: zap.NewNop(), Keys: keys, Verifier: verifier})
+	feed := feedServer(t)
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		code codes.Code
+	}{
+		{name: "no credentials", code: codes.Unauthenticated},
+		{name: "unknown API key", md: metadata.Pairs(APIKeyMetadata, "ics_unknown"), code: codes.Unauthenticated},
+		{name: "API key without scope", md: metadata.Pairs(APIKeyMetadata, admin), code: codes.PermissionDenied},
+		{name: "API key", md: metadata.Pairs(APIKeyMetadata, reader), code: codes.OK},
+		{name: "not a bearer token", md: metadata.Pairs("authorization", "Basic dXNlcg=="), code: codes.Unauthenticated},
+		{name: "wrong audience", md: metadata.Pairs("authorization", "Bearer "+sign(t, signer, "other", auth.ScopeCalendarRead)), code: codes.Unauthenticated},
+		{name: "token without scope", md: metadata.Pairs("authorization", "Bearer "+sign(t, signer, "ics", auth.ScopeAdmin)), code: codes.PermissionDenied},
+		{name: "token", md: metadata.Pairs("authorization", "Bearer "+sign(t, signer, "ics", auth.ScopeCalendarRead)), code: codes.OK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := metadata.NewOutgoingContext(context.Background(), test.md)
+			_, err := client.NextEvent(ctx, &calendarv1.NextEventRequest{Feed: &calendarv1.Feed{IcsUrl: feed}})
+			if got := status.Code(err); got != test.code {
+				t.Errorf("NextEvent code = %v (%v), want %v", got, err, test.code)
+			}
+
+			// Streams are guarded alike
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			stream, err := client.WatchNextEvent(ctx, &calendarv1.WatchNextEventRequest{Feed: &calendarv1.Feed{IcsUrl: feed}})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if got := status.Code(err); got != test.code {
+				t.Errorf("WatchNextEvent code = %v (%v), want %v", got, err, test.code)
+			}
+		})
+	}
+}
+
+func TestGuardRateLimit(t *testing.T) {
+	keys, err := apikey.NewStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reader, err := keys.Create("reader", []string{auth.ScopeCalendarRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := ratelimit.New(ratelimit.Config{
+		Anonymous:     ratelimit.Limit{Max: 2, WindowSeconds: 60},
+		Authenticated: ratelimit.Limit{Max: 3, WindowSeconds: 60},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := guardedClient(t, &Guard{Logger: zap.NewNop(), Keys: keys, Limiter: limiter})
+	req := &calendarv1.NextEventRequest{Feed: &calendarv1.Feed{IcsUrl: feedServer(t)}}
+	call := func(secret string) (metadata.MD, error) {
+		var header metadata.MD
+		ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(APIKeyMetadata, secret))
+		_, err := client.NextEvent(ctx, req, grpc.Header(&header))
+		return header, err
+	}
+
+	// Authenticated calls are counted by key, not by address
+	for i := 0; i < 3; i++ {
+		if _, err := call(reader); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	header, err := call(reader)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("fourth call code = %v, want ResourceExhausted", status.Code(err))
+	}
+	if len(header.Get(RetryAfterMetadata)) == 0 {
+		t.Errorf("rejected call sent no %s header", RetryAfterMetadata)
+	}
+
+	// Failed authentications are counted against the address, which is
+	// rejected before its credentials are checked again
+	for i := 0; i < 2; i++ {
+		if _, err := call("ics_guess"); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("guess %d code = %v, want Unauthenticated", i, status.Code(err))
+		}
+	}
+	if _, err := call("ics_guess"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("third guess code = %v, want ResourceExhausted", status.Code(err))
+	}
+}
+
+func TestGuardWithoutCredentials(t *testing.T) {
+	limiter, err := ratelimit.New(ratelimit.Config{
+		Anonymous:     ratelimit.Limit{Max: 1, WindowSeconds: 60},
+		Authenticated: ratelimit.Limit{Max: 1, WindowSeconds: 60},
+		Routes: []ratelimit.Route{
+			{Path: calendarv1.CalendarService_ListEvents_FullMethodName, Anonymous: ratelimit.Limit{Max: 2, WindowSeconds: 60}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := guardedClient(t, &Guard{Logger: zap.NewNop(), Limiter: limiter})
+	feed := &calendarv1.Feed{IcsUrl: feedServer(t)}
+
+	// Without credentials configured calls are only limited
+	if _, err := client.NextEvent(context.Background(), &calendarv1.NextEventRequest{Feed: feed}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NextEvent(context.Background(), &calendarv1.NextEventRequest{Feed: feed}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second NextEvent code = %v, want ResourceExhausted", status.Code(err))
+	}
+	// ListEvents has its own limit and bucket
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListEvents(context.Background(), &calendarv1.ListEventsRequest{Feed: feed}); err != nil {
+			t.Errorf("ListEvents %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	calendarv1 "github.com/quesurifn/ics-calendar-tidbyt-server/proto/calendar/v1"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+var states = map[t.EventState]calendarv1.State{
+	t.StateIdle:       calendarv1.State_STATE_IDLE,
+	t.StateUpcoming:   calendarv1.State_STATE_UPCOMING,
+	t.StateInProgress: calendarv1.State_STATE_IN_PROGRESS,
+}
+
+func toRequest(feed *calendarv1.Feed) t.IcsRequest {
+	return t.IcsRequest{
+		ICSUrl:           feed.GetIcsUrl(),
+		TZ:               feed.GetTz(),
+		ShowInProgress:   feed.ShowInProgress == nil || feed.GetShowInProgress(),
+		WindowDays:       int(feed.GetWindowDays()),
+		Filter:           feed.GetFilter(),
+		Width:            int(feed.GetWidth()),
+		MaxLines:         int(feed.GetMaxLines()),
+		GlyphPlaceholder: feed.GetGlyphPlaceholder(),
+	}
+}
+
+func toState(next t.NextEventResponse) *calendarv1.NextEventState {
+	state := &calendarv1.NextEventState{
+		State:       states[next.State],
+		WindowEnd:   next.WindowEnd,
+		FilteredOut: int32(next.FilteredOut),
+		RefreshAt:   next.RefreshAt,
+	}
+	if next.Event != nil {
+		state.Event = toEvent(*next.Event)
+	}
+	return state
+}
+
+func toEvent(e t.Event) *calendarv1.Event {
+	lines := make(map[string]*calendarv1.Lines, len(e.Lines))
+	for font, l := range e.Lines {
+		lines[font] = &calendarv1.Lines{Lines: l}
+	}
+
+	return &calendarv1.Event{
+		Name:              e.Name,
+		RawName:           e.RawName,
+		StartTime:         e.StartTime,
+		EndTime:           e.EndTime,
+		Location:          e.Location,
+		RawLocation:       e.RawLocation,
+		Calendar:          e.Calendar,
+		Categories:        e.Categories,
+		Foreground:        e.Colors.Foreground,
+		Background:        e.Colors.Background,
+		TenMinuteWarning:  e.TenMinuteWarning,
+		FiveMinuteWarning: e.FiveMinuteWarning,
+		OneMinuteWarning:  e.OneMinuteWarning,
+		InProgress:        e.InProgress,
+		SecondsUntilStart: e.SecondsUntilStart,
+		SecondsUntilEnd:   e.SecondsUntilEnd,
+		PercentElapsed:    e.PercentElapsed,
+		RefreshAt:         e.RefreshAt,
+		Lines:             lines,
+	}
+}
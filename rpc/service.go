@@ -0,0 +1,177 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/openapi"
+	calendarv1 "github.com/quesurifn/ics-calendar-tidbyt-server/proto/calendar/v1"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultPollInterval is how often WatchNextEvent downloads the feed again
+	defaultPollInterval = time.Minute
+	// minPollInterval keeps watchers from hammering upstream feeds
+	minPollInterval = 15 * time.Second
+)
+
+// CalendarServer implements the gRPC CalendarService on top of the calendar package
+type CalendarServer struct {
+	calendarv1.UnimplementedCalendarServiceServer
+
+	Logger   *zap.Logger
+	Calendar *c.Calendar
+}
+
+func (s CalendarServer) NextEvent(ctx context.Context, req *calendarv1.NextEventRequest) (*calendarv1.NextEventResponse, error) {
+	icsRequest, err := feedRequest(req.GetFeed())
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := s.Calendar.Next(icsRequest)
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	return &calendarv1.NextEventResponse{Next: toState(next)}, nil
+}
+
+func (s CalendarServer) ListEvents(ctx context.Context, req *calendarv1.ListEventsRequest) (*calendarv1.ListEventsResponse, error) {
+	icsRequest, err := feedRequest(req.GetFeed())
+	if err != nil {
+		return nil, err
+	}
+
+	events, filteredOut, err := s.Calendar.List(icsRequest)
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	resp := &calendarv1.ListEventsResponse{FilteredOut: int32(filteredOut)}
+	for _, e := range events {
+		resp.Events = append(resp.Events, toEvent(e))
+	}
+	return resp, nil
+}
+
+func (s CalendarServer) FreeBusy(ctx context.Context, req *calendarv1.FreeBusyRequest) (*calendarv1.FreeBusyResponse, error) {
+	icsRequest, err := feedRequest(req.GetFeed())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	events, _, err := s.Calendar.Events(icsRequest)
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	resp := &calendarv1.FreeBusyResponse{
+		WindowStart: now.Unix(),
+		WindowEnd:   s.Calendar.WindowEnd(now, icsRequest.WindowDays).Unix(),
+	}
+	for _, period := range s.Calendar.FreeBusy(events) {
+		resp.Busy = append(resp.Busy, &calendarv1.BusyPeriod{StartTime: period.StartTime, EndTime: period.EndTime})
+	}
+	return resp, nil
+}
+
+// WatchNextEvent sends the display state straight away and again whenever it
+// changes. The feed is downloaded every poll interval and at the moment the
+// display state is due to change.
+func (s CalendarServer) WatchNextEvent(req *calendarv1.WatchNextEventRequest, stream calendarv1.CalendarService_WatchNextEventServer) error {
+	icsRequest, err := feedRequest(req.GetFeed())
+	if err != nil {
+		return err
+	}
+
+	interval := defaultPollInterval
+	if req.GetPollIntervalSeconds() > 0 {
+		interval = max(time.Duration(req.GetPollIntervalSeconds())*time.Second, minPollInterval)
+	}
+
+	var last *t.NextEventResponse
+	for {
+		next, err := s.Calendar.Next(icsRequest)
+		switch {
+		case err != nil && last == nil:
+			return s.toStatus(err)
+		case err != nil:
+			s.Logger.Warn("WatchNextEvent", zap.Error(err))
+		case last == nil || c.Changed(*last, next):
+			if err := stream.Send(&calendarv1.WatchNextEventResponse{Next: toState(next)}); err != nil {
+				return err
+			}
+			last = &next
+		}
+
+		wait := interval
+		if until := time.Until(time.Unix(last.RefreshAt, 0)); until > 0 && until < wait {
+			wait = until
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// validator applies the checks of the REST API to feeds
+var validator = c.NewValidator(openapi.NewGenerator())
+
+// feedRequest converts a feed into a calendar request, rejecting feeds the
+// REST API would reject
+func feedRequest(feed *calendarv1.Feed) (t.IcsRequest, error) {
+	req := toRequest(feed)
+	violations := validator.Validate(req)
+	if len(violations) == 0 {
+		return req, nil
+	}
+
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		messages[i] = "feed." + protoName(v.Field) + " " + v.Message
+	}
+	return t.IcsRequest{}, status.Error(codes.InvalidArgument, strings.Join(messages, ", "))
+}
+
+// protoName converts the JSON name of a request field to the name of the
+// feed field it is read from, icsUrl becomes ics_url
+func protoName(field string) string {
+	var b strings.Builder
+	for _, r := range field {
+		if unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// toStatus maps calendar errors onto gRPC status codes without leaking upstream details
+func (s CalendarServer) toStatus(err error) error {
+	switch {
+	case errors.Is(err, c.ErrBadTZ):
+		return status.Error(codes.InvalidArgument, "unknown time zone, use an IANA name such as America/Chicago")
+	case errors.Is(err, c.ErrBadFilter):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, c.ErrFeedUnreachable):
+		return status.Error(codes.Unavailable, "the calendar feed could not be downloaded")
+	case errors.Is(err, c.ErrFeedNotICS):
+		return status.Error(codes.FailedPrecondition, "the calendar feed is not a valid ICS file")
+	}
+
+	s.Logger.Error("CalendarServer", zap.Error(err))
+	return status.Error(codes.Internal, "internal server error")
+}
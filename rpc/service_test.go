@@ -0,0 +1,197 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	calendarv1 "github.com/quesurifn/ics-calendar-tidbyt-server/proto/calendar/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const icsTime = "20060102T150405Z"
+
+// feedServer serves a feed with two overlapping meetings and a later one
+func feedServer(t *testing.T) string {
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Minute)
+	event := func(name string, from time.Time, length time.Duration) string {
+		return fmt.Sprintf("BEGIN:VEVENT\r\nUID:%s\r\nDTSTAMP:%s\r\nSUMMARY:%s\r\nDTSTART:%s\r\nDTEND:%s\r\nEND:VEVENT\r\n",
+			name, start.Format(icsTime), name, from.Format(icsTime), from.Add(length).Format(icsTime))
+	}
+	feed := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" +
+		event("Standup", start, 30*time.Minute) +
+		event("Review", start.Add(15*time.Minute), 30*time.Minute) +
+		event("Retro", start.Add(3*time.Hour), time.Hour) +
+		"END:VCALENDAR\r\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		io.WriteString(w, feed)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/cal.ics"
+}
+
+// newClient serves a CalendarServer with opts over an in-memory connection
+func newClient(t *testing.T, opts ...grpc.ServerOption) calendarv1.CalendarServiceClient {
+	logger := zap.NewNop()
+	ln := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(opts...)
+	calendarv1.RegisterCalendarServiceServer(server, CalendarServer{
+		Logger:   logger,
+		Calendar: &calendar.Calendar{Logger: logger},
+	})
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return calendarv1.NewCalendarServiceClient(conn)
+}
+
+func TestNextEvent(t *testing.T) {
+	client := newClient(t)
+	resp, err := client.NextEvent(context.Background(), &calendarv1.NextEventRequest{
+		Feed: &calendarv1.Feed{IcsUrl: feedServer(t)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := resp.GetNext()
+	if next.GetState() != calendarv1.State_STATE_UPCOMING {
+		t.Errorf("state = %v, want upcoming", next.GetState())
+	}
+	if name := next.GetEvent().GetName(); name != "Standup" {
+		t.Errorf("next event = %q, want Standup", name)
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	client := newClient(t)
+	resp, err := client.ListEvents(context.Background(), &calendarv1.ListEventsRequest{
+		Feed: &calendarv1.Feed{IcsUrl: feedServer(t), Filter: "-name:Retro"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, e := range resp.GetEvents() {
+		names = append(names, e.GetName())
+	}
+	if fmt.Sprint(names) != "[Standup Review]" {
+		t.Errorf("events = %v, want [Standup Review]", names)
+	}
+	if resp.GetFilteredOut() != 1 {
+		t.Errorf("filteredOut = %d, want 1", resp.GetFilteredOut())
+	}
+}
+
+func TestFreeBusy(t *testing.T) {
+	client := newClient(t)
+	resp, err := client.FreeBusy(context.Background(), &calendarv1.FreeBusyRequest{
+		Feed: &calendarv1.Feed{IcsUrl: feedServer(t)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Standup and Review overlap and merge into one period
+	busy := resp.GetBusy()
+	if len(busy) != 2 {
+		t.Fatalf("busy = %v, want 2 periods", busy)
+	}
+	if length := busy[0].GetEndTime() - busy[0].GetStartTime(); length != int64(45*time.Minute/time.Second) {
+		t.Errorf("first period lasts %ds, want 45m", length)
+	}
+	if resp.GetWindowEnd() <= resp.GetWindowStart() {
+		t.Errorf("window %d-%d is empty", resp.GetWindowStart(), resp.GetWindowEnd())
+	}
+}
+
+func TestStatusCodes(t *testing.T) {
+	client := newClient(t)
+	feedURL := feedServer(t)
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name    string
+		feed    *calendarv1.Feed
+		code    codes.Code
+		message string
+	}{
+		{name: "missing url", feed: &calendarv1.Feed{}, code: codes.InvalidArgument, message: "feed.ics_url is required"},
+		{name: "scheme", feed: &calendarv1.Feed{IcsUrl: "ftp://example.com/cal.ics"}, code: codes.InvalidArgument,
+			message: "feed.ics_url must use the http, https or webcal scheme"},
+		{name: "unknown tz", feed: &calendarv1.Feed{IcsUrl: feedURL, Tz: "Mars/Olympus"}, code: codes.InvalidArgument,
+			message: "feed.tz must be an IANA time zone"},
+		{name: "window", feed: &calendarv1.Feed{IcsUrl: feedURL, WindowDays: 400}, code: codes.InvalidArgument,
+			message: "feed.window_days must be at most 31"},
+		{name: "max lines", feed: &calendarv1.Feed{IcsUrl: feedURL, MaxLines: 20}, code: codes.InvalidArgument,
+			message: "feed.max_lines must be at most 8"},
+		{name: "placeholder", feed: &calendarv1.Feed{IcsUrl: feedURL, GlyphPlaceholder: "[unknown]"}, code: codes.InvalidArgument,
+			message: "feed.glyph_placeholder must be at most 4 characters"},
+		{name: "bad filter", feed: &calendarv1.Feed{IcsUrl: feedURL, Filter: "colour:red"}, code: codes.InvalidArgument},
+		{name: "unreachable", feed: &calendarv1.Feed{IcsUrl: unreachable.URL + "/cal.ics"}, code: codes.Unavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := client.NextEvent(context.Background(), &calendarv1.NextEventRequest{Feed: test.feed})
+			if code := status.Code(err); code != test.code {
+				t.Errorf("code = %v, want %v (%v)", code, test.code, err)
+			}
+			if msg := status.Convert(err).Message(); !strings.Contains(msg, test.message) {
+				t.Errorf("message = %q, want %q", msg, test.message)
+			}
+		})
+	}
+}
+
+func TestWatchNextEvent(t *testing.T) {
+	client := newClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.WatchNextEvent(ctx, &calendarv1.WatchNextEventRequest{
+		Feed: &calendarv1.Feed{IcsUrl: feedServer(t)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := resp.GetNext().GetEvent().GetName(); name != "Standup" {
+		t.Errorf("first state is for %q, want Standup", name)
+	}
+
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Errorf("Recv after cancel = %v, want Canceled", err)
+	}
+}
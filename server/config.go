@@ -0,0 +1,233 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/mqtt"
+	pkgconfig "github.com/quesurifn/ics-calendar-tidbyt-server/pkg/config"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/envelope"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ratelimit"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/theme"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/tlsconfig"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// Config holds every runtime setting of the server. It is loaded from
+// config.yml by pkg/config and every field can be set from the environment,
+// e.g. ICS_SRV_PORT or ICS_SRV_RATELIMIT_ANONYMOUS_MAX.
+// Secrets can be kept out of both: ICS_SRV_ADMINTOKEN_FILE reads the token
+// from a mounted file, and values written as env:NAME or file:PATH in
+// config.yml are read from the environment or a file.
+type Config struct {
+	AppName string
+
+	// Host is the address to listen on, every interface when empty
+	Host string
+	// Port is checked to be between 1 and 65535 by Validate
+	Port string `required:"true" regexp:"^[0-9]+$"`
+	Env  string
+	Log  LogConfig
+	// Timeouts bound client connections, zero means no limit
+	Timeouts Timeouts
+
+	Theme []t.ColorRule
+	// TZMap maps the Windows time zone names some feeds use to IANA names
+	TZMap map[string]string
+	// MaxLookaheadDays caps the window requests may ask for, at most 31
+	MaxLookaheadDays int `min:"0" max:"31"`
+	Fetch            FetchConfig
+
+	// JWT guards the calendar and admin routes with bearer tokens when
+	// PublicKey is set
+	JWT JWTConfig
+	// APIKeys guards the calendar and admin routes with X-API-Key keys when
+	// Store is set, alongside JWT when it is configured
+	APIKeys APIKeysConfig
+	// AdminToken is the bearer token of the /admin routes when neither JWT
+	// nor API keys are configured, they are not served when all are empty
+	AdminToken string `secret:"true"`
+	Webhooks   WebhooksConfig
+	Exports    ExportsConfig
+	MQTT       mqtt.Config
+	// Encryption seals the feed URLs and webhook secrets of the stores
+	Encryption EncryptionConfig
+	// TLS serves HTTPS when a certificate is set, and verifies client
+	// certificates when a client CA is set
+	TLS tlsconfig.Config
+	// ClientIdentities grant scopes to client certificates by subject
+	ClientIdentities []auth.CertIdentity
+	// TrustedProxies are the CIDR ranges of the proxies in front of the
+	// server, X-Forwarded-For is only believed when they send it
+	TrustedProxies []string `cidr:"true"`
+	RateLimit      ratelimit.Config
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error. Event details are only logged at
+	// debug level.
+	Level string `oneof:"debug info warn error"`
+}
+
+type Timeouts struct {
+	ReadSeconds int `min:"0"`
+	// WriteSeconds also bounds next-event streams, leave it at zero when
+	// clients stream
+	WriteSeconds int `min:"0"`
+	IdleSeconds  int `min:"0"`
+	// ShutdownSeconds is how long open requests get to finish on shutdown
+	ShutdownSeconds int `min:"0"`
+}
+
+// FetchConfig limits feed downloads
+type FetchConfig struct {
+	TimeoutSeconds int   `min:"0"`
+	MaxBytes       int64 `min:"0"`
+}
+
+type JWTConfig struct {
+	// PublicKey is a PEM file holding the key tokens are signed with, or a
+	// JWKS file holding keys by kid. It is read again when it changes.
+	PublicKey string
+	// Audience must be in the aud claim of tokens when set
+	Audience string
+	// Issuer must be the iss claim of tokens when set
+	Issuer string
+}
+
+type APIKeysConfig struct {
+	// Store is the JSON file API keys are kept in, it is shared with the
+	// keys command
+	Store string
+}
+
+type WebhooksConfig struct {
+	// Store is the JSON file subscriptions and deliveries are kept in, they
+	// are kept in memory when it is empty
+	Store           string
+	IntervalSeconds int `min:"0"`
+}
+
+type ExportsConfig struct {
+	// Store is the JSON file export feeds are kept in, they are kept in
+	// memory when it is empty
+	Store string
+}
+
+type EncryptionConfig struct {
+	// Keys are id:base64 master keys separated by commas, the first one
+	// encrypts and the rest only decrypt. To rotate, put a new key first and
+	// restart every server, the stores are sealed with it on start up, then
+	// drop the old key.
+	Keys string `secret:"true"`
+	// KeyFile holds the keys one per line when Keys is empty
+	KeyFile string
+}
+
+// Keyring loads the master keys, it returns nil when encryption is not
+// configured
+func (e EncryptionConfig) Keyring() (*envelope.Keyring, error) {
+	return envelope.Load(e.Keys, e.KeyFile)
+}
+
+// DefaultConfig returns the settings used for everything config.yml and the
+// environment leave out
+func DefaultConfig() Config {
+	return Config{
+		AppName: "Tidbyt ICS Server",
+		Port:    "8080",
+		Log:     LogConfig{Level: "info"},
+		Timeouts: Timeouts{
+			ReadSeconds:     30,
+			IdleSeconds:     120,
+			ShutdownSeconds: 10,
+		},
+		TZMap: map[string]string{
+			"Hawaii Standard Time":     "Pacific/Honolulu",
+			"Alaskan Standard Time":    "America/Anchorage",
+			"Alaskan Daylight Time":    "America/Anchorage",
+			"SA Pacific Standard Time": "America/Bogota",
+			"Pacific Standard Time":    "America/Los_Angeles",
+			"Pacific Daylight Time":    "America/Los_Angeles",
+			"Central Standard Time":    "America/Chicago",
+			"Central Daylight Time":    "America/Chicago",
+			"Mountain Standard Time":   "America/Denver",
+			"Mountain Daylight Time":   "America/Denver",
+			"Eastern Standard Time":    "America/New_York",
+			"Eastern Daylight Time":    "America/New_York",
+		},
+		MaxLookaheadDays: 31,
+		Fetch: FetchConfig{
+			TimeoutSeconds: 30,
+			MaxBytes:       10 << 20,
+		},
+		RateLimit: ratelimit.Config{
+			Exempt:        []string{"127.0.0.1", "::1"},
+			Anonymous:     ratelimit.Limit{Max: 20, WindowSeconds: 30},
+			Authenticated: ratelimit.Limit{Max: 120, WindowSeconds: 30},
+		},
+	}
+}
+
+// Validate checks what the tags of Config cannot express, pkg/config calls it
+// on load and reload
+func (config Config) Validate() error {
+	var errs []error
+	fail := func(path string, err error) {
+		errs = append(errs, &pkgconfig.FieldError{Path: path, Err: err})
+	}
+
+	// The regexp tag reports ports that are not numbers
+	if port, err := strconv.Atoi(config.Port); err == nil && (port < 1 || port > 65535) {
+		fail("Port", fmt.Errorf("%d must be between 1 and 65535", port))
+	}
+	for i, rule := range config.Theme {
+		if _, err := theme.Parse(rule.Color); err != nil {
+			fail(fmt.Sprintf("Theme[%d].Color", i), err)
+		}
+	}
+	for _, name := range sortedKeys(config.TZMap) {
+		if _, err := time.LoadLocation(config.TZMap[name]); err != nil {
+			fail(fmt.Sprintf("TZMap[%s]", name), err)
+		}
+	}
+
+	checkLimit := func(path string, limit ratelimit.Limit) {
+		if limit.Max <= 0 || limit.WindowSeconds <= 0 {
+			fail(path, errors.New("max and windowseconds must be positive"))
+		}
+	}
+	checkLimit("RateLimit.Anonymous", config.RateLimit.Anonymous)
+	checkLimit("RateLimit.Authenticated", config.RateLimit.Authenticated)
+	for i, route := range config.RateLimit.Routes {
+		path := fmt.Sprintf("RateLimit.Routes[%d]", i)
+		if route.Path == "" {
+			fail(path+".Path", errors.New("is required, but blank"))
+		}
+		// Zero limits of a route fall back to the defaults
+		if route.Anonymous != (ratelimit.Limit{}) {
+			checkLimit(path+".Anonymous", route.Anonymous)
+		}
+		if route.Authenticated != (ratelimit.Limit{}) {
+			checkLimit(path+".Authenticated", route.Authenticated)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
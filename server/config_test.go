@@ -0,0 +1,38 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	pkgconfig "github.com/quesurifn/ics-calendar-tidbyt-server/pkg/config"
+)
+
+func TestConfigPort(t *testing.T) {
+	tests := []struct {
+		port string
+		want string
+	}{
+		{port: "1"},
+		{port: "8080"},
+		{port: "65535"},
+		{port: "", want: "Port: is required, but blank"},
+		{port: "0", want: "Port: 0 must be between 1 and 65535"},
+		{port: "65536", want: "Port: 65536 must be between 1 and 65535"},
+		{port: "99999", want: "Port: 99999 must be between 1 and 65535"},
+		{port: "http", want: `Port: "http" must match ^[0-9]+$`},
+	}
+	for _, test := range tests {
+		t.Run(test.port, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Port = test.port
+
+			err := pkgconfig.Validate(&config)
+			switch {
+			case test.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+				t.Errorf("got %v, want %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/redact"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newLogger builds the production logger at level, with feed secrets
+// redacted from every entry
+func newLogger(level zap.AtomicLevel) (*zap.Logger, error) {
+	logConfig := zap.NewProductionConfig()
+	logConfig.Level = level
+	return logConfig.Build(zap.WrapCore(redact.WrapCore))
+}
+
+// logLevel returns the level of a validated configuration
+func logLevel(config Config) zapcore.Level {
+	level, _ := zapcore.ParseLevel(config.Log.Level)
+	return level
+}
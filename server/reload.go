@@ -0,0 +1,92 @@
+package server
+
+import (
+	"reflect"
+
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	pkgconfig "github.com/quesurifn/ics-calendar-tidbyt-server/pkg/config"
+	"go.uber.org/zap"
+)
+
+// reloadable are the sections of Config that Reload applies to a running
+// server, the others are only read by New
+var reloadable = map[string]bool{
+	"Log":              true,
+	"Theme":            true,
+	"TZMap":            true,
+	"MaxLookaheadDays": true,
+	"Fetch":            true,
+	"RateLimit":        true,
+}
+
+// Reload applies a new configuration to the running server. A configuration
+// that fails validation is rejected and the current one stays in place.
+// Sections that need a restart keep their current values, a warning names
+// them.
+func (s *Server) Reload(next Config) error {
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+
+	if err := pkgconfig.Validate(&next); err != nil {
+		s.Logger.Error("Config reload rejected", zap.Error(err))
+		return err
+	}
+
+	current := s.Config()
+	applied, pending := diff(current, next)
+	if len(pending) > 0 {
+		s.Logger.Warn("Config changes need a restart", zap.Strings("sections", pending))
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	// Keep what needs a restart, so the config reflects what is running
+	updated := current
+	for _, name := range applied {
+		reflect.ValueOf(&updated).Elem().FieldByName(name).Set(reflect.ValueOf(next).FieldByName(name))
+	}
+	if err := s.limiter.Reconfigure(updated.RateLimit); err != nil {
+		s.Logger.Error("Config reload rejected", zap.Error(err))
+		return err
+	}
+	s.Calendar.Reconfigure(CalendarSettings(updated))
+	s.level.SetLevel(logLevel(updated))
+	s.config.Store(&updated)
+
+	fields := []zap.Field{zap.Strings("sections", applied)}
+	for _, name := range applied {
+		fields = append(fields, zap.Any(name, reflect.ValueOf(updated).FieldByName(name).Interface()))
+	}
+	s.Logger.Info("Config reloaded", fields...)
+	return nil
+}
+
+// diff returns the names of the sections that differ between configurations,
+// split by whether Reload can apply them
+func diff(current, next Config) (applied, pending []string) {
+	a, b := reflect.ValueOf(current), reflect.ValueOf(next)
+	for i := 0; i < a.NumField(); i++ {
+		if reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			continue
+		}
+		name := a.Type().Field(i).Name
+		if reloadable[name] {
+			applied = append(applied, name)
+		} else {
+			pending = append(pending, name)
+		}
+	}
+	return applied, pending
+}
+
+// CalendarSettings returns the calendar settings of a configuration
+func CalendarSettings(config Config) c.Settings {
+	return c.Settings{
+		ColorRules:       config.Theme,
+		TZMap:            config.TZMap,
+		MaxLookaheadDays: config.MaxLookaheadDays,
+		FetchTimeout:     seconds(config.Fetch.TimeoutSeconds),
+		MaxFeedBytes:     config.Fetch.MaxBytes,
+	}
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/contrib/fiberzap/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/apikey"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/auth"
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/export"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/gql"
+	h "github.com/quesurifn/ics-calendar-tidbyt-server/handlers"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/mqtt"
+	pkgconfig "github.com/quesurifn/ics-calendar-tidbyt-server/pkg/config"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/netutil"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ratelimit"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/tlsconfig"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/webhook"
+	"go.uber.org/zap"
+)
+
+// Server is the HTTP server assembled from a Config
+type Server struct {
+	Logger   *zap.Logger
+	Calendar *c.Calendar
+	// App serves the routes, requests can be sent to it with App.Test
+	// without listening
+	App *fiber.App
+
+	config     atomic.Pointer[Config]
+	reloading  sync.Mutex
+	level      zap.AtomicLevel
+	limiter    *ratelimit.Limiter
+	tls        *tls.Config
+	dispatcher *webhook.Dispatcher
+	publisher  *mqtt.Publisher
+}
+
+// New builds the logger, stores, middleware and routes of a server. Nothing
+// runs until Run.
+func New(config Config) (*Server, error) {
+	if err := pkgconfig.Validate(&config); err != nil {
+		return nil, err
+	}
+
+	s := &Server{level: zap.NewAtomicLevel()}
+	s.config.Store(&config)
+	s.level.SetLevel(logLevel(config))
+	logger, err := newLogger(s.level)
+	if err != nil {
+		return nil, err
+	}
+	s.Logger = logger
+	s.Calendar = &c.Calendar{
+		Logger:   logger,
+		Settings: CalendarSettings(config),
+	}
+
+	keys, err := config.Encryption.Keyring()
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil && (config.Webhooks.Store != "" || config.Exports.Store != "") {
+		logger.Warn("Encryption", zap.String("err", "no master keys, stored feed URLs and secrets are not encrypted"))
+	}
+
+	webhooks, err := webhook.NewStore(config.Webhooks.Store, keys)
+	if err != nil {
+		return nil, err
+	}
+	s.dispatcher = &webhook.Dispatcher{
+		Logger:   logger,
+		Calendar: s.Calendar,
+		Store:    webhooks,
+		Interval: seconds(config.Webhooks.IntervalSeconds),
+	}
+	if config.MQTT.Broker != "" {
+		s.publisher = &mqtt.Publisher{
+			Logger:   logger,
+			Calendar: s.Calendar,
+			Config:   config.MQTT,
+		}
+	}
+
+	exports, err := export.NewStore(config.Exports.Store, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.TLS.Enabled() {
+		if s.tls, err = tlsconfig.New(config.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	handlers := h.Handlers{
+		Logger:   logger,
+		Calendar: s.Calendar,
+		Webhooks: webhooks,
+		Exports:  exports,
+	}
+	s.App = fiber.New(fiber.Config{
+		AppName:      config.AppName,
+		ErrorHandler: handlers.ErrorHandler,
+		ReadTimeout:  seconds(config.Timeouts.ReadSeconds),
+		WriteTimeout: seconds(config.Timeouts.WriteSeconds),
+		IdleTimeout:  seconds(config.Timeouts.IdleSeconds),
+	})
+	if s.limiter, err = ratelimit.New(config.RateLimit); err != nil {
+		return nil, err
+	}
+	if err := s.routes(handlers); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Config returns the current configuration of the server
+func (s *Server) Config() Config {
+	return *s.config.Load()
+}
+
+// routes registers the middleware and routes of the app
+func (s *Server) routes(handlers h.Handlers) error {
+	config, app, limiter := s.Config(), s.App, s.limiter
+
+	proxies, err := netutil.ParseNetworks(config.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	app.Use(requestid.New())
+	app.Use(handlers.ClientIP(proxies))
+	app.Use(fiberzap.New(fiberzap.Config{
+		Logger: s.Logger,
+	}))
+
+	// With client certificates, JWT or API keys configured, calendar routes
+	// need the calendar:read scope and admin routes the admin scope
+	var authenticate []fiber.Handler
+	if config.TLS.ClientCA != "" {
+		authenticate = append(authenticate, handlers.ClientCertAuth(config.ClientIdentities))
+	}
+	if config.APIKeys.Store != "" {
+		keys, err := apikey.NewStore(config.APIKeys.Store)
+		if err != nil {
+			return err
+		}
+		authenticate = append(authenticate, handlers.APIKeyAuth(keys))
+	}
+	if config.JWT.PublicKey != "" {
+		keys, err := auth.NewKeySet(config.JWT.PublicKey)
+		if err != nil {
+			return err
+		}
+		authenticate = append(authenticate, handlers.Authenticate(&auth.Verifier{
+			Keys:     keys,
+			Audience: config.JWT.Audience,
+			Issuer:   config.JWT.Issuer,
+		}))
+	}
+	var calendarAuth, adminAuth []fiber.Handler
+	if authenticate != nil {
+		calendarAuth = append(authenticate[:len(authenticate):len(authenticate)], handlers.RequireScopes(auth.ScopeCalendarRead))
+		adminAuth = append(authenticate[:len(authenticate):len(authenticate)], handlers.RequireScopes(auth.ScopeAdmin))
+	} else if config.AdminToken != "" {
+		adminAuth = []fiber.Handler{handlers.AdminAuth(config.AdminToken)}
+	}
+	// limited runs the guards of a route, then its rate limit, which is keyed
+	// by the identity the guards authenticated. Requests failing the guards
+	// are limited by address ahead of them.
+	limited := func(path string, guards []fiber.Handler) []fiber.Handler {
+		var chain []fiber.Handler
+		if len(guards) > 0 {
+			chain = append(chain, handlers.AuthFailureLimit(limiter, path))
+		}
+		chain = append(chain, guards...)
+		return append(chain, handlers.RateLimit(limiter, path))
+	}
+	guard := func(path string, guards []fiber.Handler, handler fiber.Handler) []fiber.Handler {
+		return append(limited(path, guards), handler)
+	}
+
+	app.Get("/", guard("/", nil, handlers.RootHandler)...)
+	app.Get("/openapi.json", guard("/openapi.json", nil, handlers.OpenAPIHandler)...)
+	app.Get("/docs", guard("/docs", nil, handlers.DocsHandler)...)
+
+	// Unversioned routes are aliases of v1, which keeps the original shape
+	calendarRoutes := func(r fiber.Router) {
+		r.Get("/ics/next-event", guard("/ics/next-event", calendarAuth, handlers.NextEventQueryHandler)...)
+		r.Post("/ics/next-event", guard("/ics/next-event", calendarAuth, handlers.NextEventHandler)...)
+		r.Get("/ics/next-event/stream", guard("/ics/next-event/stream", calendarAuth, handlers.NextEventStreamHandler)...)
+	}
+	calendarRoutes(app)
+	calendarRoutes(app.Group("/v1", handlers.Version(1)))
+	calendarRoutes(app.Group("/v2", handlers.Version(2)))
+	app.Get("/ics/export/:token.ics", guard("/ics/export", nil, handlers.ExportHandler)...)
+	if adminAuth != nil {
+		admin := app.Group("/admin", limited("/admin", adminAuth)...)
+		admin.Get("/webhooks", handlers.ListWebhooksHandler)
+		admin.Post("/webhooks", handlers.CreateWebhookHandler)
+		admin.Get("/webhooks/deliveries", handlers.WebhookDeliveriesHandler)
+		admin.Delete("/webhooks/:id", handlers.DeleteWebhookHandler)
+		admin.Get("/exports", handlers.ListExportsHandler)
+		admin.Post("/exports", handlers.CreateExportHandler)
+		admin.Delete("/exports/:id", handlers.DeleteExportHandler)
+	}
+	app.All("/graphql", guard("/graphql", calendarAuth, adaptor.HTTPHandler(gql.Handler(&gql.Resolver{
+		Logger: s.Logger,
+		ICS:    s.Calendar,
+	})))...)
+
+	return nil
+}
+
+// Address is the host and port the server listens on
+func (s *Server) Address() string {
+	config := s.Config()
+	return net.JoinHostPort(config.Host, config.Port)
+}
+
+// Run starts the background workers and serves requests until ctx is done,
+// open requests get Timeouts.ShutdownSeconds to finish or as long as they
+// need when it is zero
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Address())
+	if err != nil {
+		return err
+	}
+	if s.tls != nil {
+		ln = tls.NewListener(ln, s.tls)
+	}
+
+	go s.dispatcher.Run(ctx)
+	if s.publisher != nil {
+		go s.publisher.Run(ctx)
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown := s.App.Shutdown
+		if timeout := seconds(s.Config().Timeouts.ShutdownSeconds); timeout > 0 {
+			shutdown = func() error { return s.App.ShutdownWithTimeout(timeout) }
+		}
+		if err := shutdown(); err != nil {
+			s.Logger.Error("Shutdown", zap.Error(err))
+		}
+	}()
+
+	s.Logger.Info("Listening", zap.String("address", s.Address()), zap.Bool("tls", s.tls != nil))
+	return s.App.Listener(ln)
+}
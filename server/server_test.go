@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/ratelimit"
+)
+
+func TestNew(t *testing.T) {
+	config := DefaultConfig()
+	config.Host = "127.0.0.1"
+	config.Port = "9091"
+	config.Log.Level = "error"
+	config.RateLimit = ratelimit.Config{
+		Anonymous:     ratelimit.Limit{Max: 2, WindowSeconds: 60},
+		Authenticated: ratelimit.Limit{Max: 10, WindowSeconds: 60},
+		Routes: []ratelimit.Route{
+			{Path: "/docs", Anonymous: ratelimit.Limit{Max: 1, WindowSeconds: 60}},
+		},
+	}
+
+	srv, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Address(); got != "127.0.0.1:9091" {
+		t.Errorf("Address() = %q, want 127.0.0.1:9091", got)
+	}
+
+	steps := []struct {
+		path   string
+		status int
+		limit  string
+	}{
+		{path: "/", status: fiber.StatusOK, limit: "2"},
+		{path: "/openapi.json", status: fiber.StatusOK, limit: "2"},
+		// the default bucket is shared by the routes without their own limits
+		{path: "/", status: fiber.StatusTooManyRequests, limit: "2"},
+		// /docs has its own limit and bucket
+		{path: "/docs", status: fiber.StatusOK, limit: "1"},
+		{path: "/docs", status: fiber.StatusTooManyRequests, limit: "1"},
+	}
+	for i, step := range steps {
+		resp, err := srv.App.Test(httptest.NewRequest("GET", step.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != step.status {
+			t.Errorf("request %d to %s: status %d, want %d", i, step.path, resp.StatusCode, step.status)
+		}
+		if got := resp.Header.Get("RateLimit-Limit"); got != step.limit {
+			t.Errorf("request %d to %s: RateLimit-Limit %q, want %q", i, step.path, got, step.limit)
+		}
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Port = ""
+	config.Log.Level = "loud"
+
+	if _, err := New(config); err == nil {
+		t.Fatal("New accepted a config without a port and with an unknown log level")
+	}
+}
+
+func TestRunListensOnPort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	config := DefaultConfig()
+	config.Host = "127.0.0.1"
+	config.Port = port
+	config.Log.Level = "error"
+	srv, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	url := "http://127.0.0.1:" + port + "/"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("GET %s: status %d", url, resp.StatusCode)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("nothing listens on %s: %v", url, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+}
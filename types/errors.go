@@ -0,0 +1,30 @@
+package types
+
+// ErrorCode is the machine readable reason a request failed
+type ErrorCode string
+
+const (
+	ErrBadRequest       ErrorCode = "BAD_REQUEST"
+	ErrValidation       ErrorCode = "VALIDATION_FAILED"
+	ErrUnauthorized     ErrorCode = "UNAUTHORIZED"
+	ErrForbidden        ErrorCode = "FORBIDDEN"
+	ErrNotFound         ErrorCode = "NOT_FOUND"
+	ErrMethodNotAllowed ErrorCode = "METHOD_NOT_ALLOWED"
+	ErrFeedUnreachable  ErrorCode = "FEED_UNREACHABLE"
+	ErrFeedNotICS       ErrorCode = "FEED_NOT_ICS"
+	ErrBadTZ            ErrorCode = "BAD_TZ"
+	ErrRateLimited      ErrorCode = "RATE_LIMITED"
+	ErrNoEvents         ErrorCode = "NO_EVENTS"
+	ErrInternal         ErrorCode = "INTERNAL"
+)
+
+type ErrorBody struct {
+	Code    ErrorCode    `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,22 @@
+package types
+
+// TransitionKind is a change of an event that subscribers can be notified about
+type TransitionKind string
+
+const (
+	TransitionThreshold   TransitionKind = "threshold"
+	TransitionStarted     TransitionKind = "started"
+	TransitionEnded       TransitionKind = "ended"
+	TransitionRescheduled TransitionKind = "rescheduled"
+	TransitionCancelled   TransitionKind = "cancelled"
+)
+
+// Transition is a change of an event between two looks at its feed
+type Transition struct {
+	Kind TransitionKind `json:"kind" enum:"threshold started ended rescheduled cancelled"`
+	// Threshold is the number of seconds before the start that was crossed
+	Threshold int64  `json:"threshold,omitempty"`
+	At        int64  `json:"at"`
+	Event     Event  `json:"event"`
+	Previous  *Event `json:"previous,omitempty"`
+}
@@ -1,25 +1,82 @@
 package types
 
 type Event struct {
+	UID               string
+	RecurrenceID      int64
+	Cancelled         bool
 	Name              string
+	RawName           string
 	StartTime         int64
 	EndTime           int64
 	Location          *string
+	RawLocation       *string
+	Calendar          string
+	Categories        []string
+	Colors            Colors
 	TenMinuteWarning  bool
 	FiveMinuteWarning bool
 	OneMinuteWarning  bool
 	InProgress        bool
+	SecondsUntilStart int64
+	SecondsUntilEnd   int64
+	PercentElapsed    float64
+	RefreshAt         int64
+	RefreshInSeconds  int64
+	Lines             map[string][]string
+}
+
+type EventState string
+
+const (
+	StateIdle       EventState = "idle"
+	StateUpcoming   EventState = "upcoming"
+	StateInProgress EventState = "in_progress"
+)
+
+type NextEventResponse struct {
+	State            EventState `json:"state" enum:"idle upcoming in_progress"`
+	Event            *Event     `json:"event"`
+	WindowEnd        int64      `json:"windowEnd"`
+	FilteredOut      int        `json:"filteredOut"`
+	RefreshAt        int64      `json:"refreshAt"`
+	RefreshInSeconds int64      `json:"refreshInSeconds"`
+}
+
+type BusyPeriod struct {
+	StartTime int64 `json:"startTime"`
+	EndTime   int64 `json:"endTime"`
+}
+
+type Colors struct {
+	Source     string
+	Foreground string
+	Background string
+}
+
+type ColorRule struct {
+	Calendar string `json:"calendar" query:"calendar" doc:"Calendar name the rule applies to"`
+	Category string `json:"category" query:"category" doc:"Category the rule applies to"`
+	Color    string `json:"color" query:"color" required:"true" format:"color" doc:"Hex color or CSS color name"`
 }
 
 type BaseResponse[t any] struct {
-	Data    t      `json:"data"`
-	Message string `json:"message"`
+	Data      t          `json:"data"`
+	Message   string     `json:"message"`
+	Error     *ErrorBody `json:"error,omitempty"`
+	RequestID string     `json:"requestId"`
 }
 
 type IcsRequest struct {
-	ICSUrl         string `json:"icsUrl"`
-	ShowInProgress bool   `json:"showInProgress"`
-	TZ             string `json:"tz"`
+	ICSUrl         string `json:"icsUrl" query:"icsUrl" required:"true" format:"uri" secret:"true" doc:"http, https or webcal URL of the ICS feed"`
+	ShowInProgress bool   `json:"showInProgress" query:"showInProgress" doc:"Keep events that have already started, defaults to true"`
+	TZ             string `json:"tz" query:"tz" format:"iana-tz" doc:"IANA time zone of the display, defaults to UTC"`
+	WindowDays     int    `json:"windowDays" query:"windowDays" minimum:"1" maximum:"31" doc:"Lookahead window in days, defaults to 7"`
+	Filter         string `json:"filter" query:"filter" format:"filter" maxLength:"512" doc:"Comma separated field:value terms, prefix with - to exclude"`
+	Width          int    `json:"width" query:"width" minimum:"1" maximum:"256" doc:"Width in pixels to lay event names out for, defaults to 64"`
+	MaxLines       int    `json:"maxLines" query:"maxLines" minimum:"1" maximum:"8" doc:"Lines an event name may wrap onto, defaults to 2"`
+
+	GlyphPlaceholder string      `json:"glyphPlaceholder" query:"glyphPlaceholder" maxLength:"4" doc:"Replaces characters the fonts cannot draw"`
+	Theme            []ColorRule `json:"theme" query:"theme" doc:"Color rules applied after the server rules"`
 }
 
 type IcsResponse struct {
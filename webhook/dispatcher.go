@@ -0,0 +1,265 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	apiv2 "github.com/quesurifn/ics-calendar-tidbyt-server/api/v2"
+	c "github.com/quesurifn/ics-calendar-tidbyt-server/calendar"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/redact"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultInterval is how often feeds are checked when the dispatcher has no
+	// interval and no event is due to change sooner
+	DefaultInterval = 30 * time.Second
+	// DefaultAttempts is how many times a delivery is tried before it fails
+	DefaultAttempts = 5
+	// DefaultBackoff is the wait before the first retry, it doubles every retry
+	DefaultBackoff = 2 * time.Second
+	// minWait keeps the dispatcher from spinning on an event that just ended
+	minWait = time.Second
+
+	SignatureHeader = "X-Webhook-Signature"
+	IDHeader        = "X-Webhook-Id"
+)
+
+type DeliveryStatus string
+
+const (
+	StatusPending   DeliveryStatus = "pending"
+	StatusDelivered DeliveryStatus = "delivered"
+	StatusFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is an entry of the delivery log
+type Delivery struct {
+	ID             string           `json:"id"`
+	SubscriptionID string           `json:"subscriptionId"`
+	Transition     t.TransitionKind `json:"transition"`
+	EventName      string           `json:"eventName"`
+	Status         DeliveryStatus   `json:"status" enum:"pending delivered failed"`
+	Attempts       int              `json:"attempts"`
+	StatusCode     int              `json:"statusCode,omitempty"`
+	// Error is why the last attempt failed. It never holds the subscription
+	// URL, which is as secret as the signing secret.
+	Error     string `json:"error,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
+
+// Payload is the body posted to a subscription's URL, the transition is in
+// its API v2 shape
+type Payload struct {
+	ID             string           `json:"id"`
+	SubscriptionID string           `json:"subscriptionId"`
+	Transition     apiv2.Transition `json:"transition"`
+	SentAt         int64            `json:"sentAt"`
+}
+
+// Dispatcher checks the feed of every subscription on a timer and posts the
+// transitions they asked for
+type Dispatcher struct {
+	Logger   *zap.Logger
+	Calendar *c.Calendar
+	Store    *Store
+	Interval time.Duration
+	Attempts int
+	Backoff  time.Duration
+
+	client    *resty.Client
+	snapshots map[string]snapshot
+}
+
+// snapshot is the last look at a subscription's feed
+type snapshot struct {
+	events []t.Event
+	at     int64
+}
+
+// Run checks the feeds until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	if d.Interval <= 0 {
+		d.Interval = DefaultInterval
+	}
+	if d.Attempts <= 0 {
+		d.Attempts = DefaultAttempts
+	}
+	if d.Backoff <= 0 {
+		d.Backoff = DefaultBackoff
+	}
+	d.client = resty.New().SetTimeout(10 * time.Second)
+	d.snapshots = map[string]snapshot{}
+
+	for {
+		wait := d.check(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// check looks at every feed once and returns how long to wait before the next look
+func (d *Dispatcher) check(ctx context.Context, now time.Time) time.Duration {
+	wait := d.Interval
+	seen := map[string]bool{}
+
+	for _, sub := range d.Store.Subscriptions() {
+		seen[sub.ID] = true
+
+		req := sub.Feed
+		req.ShowInProgress = true
+		events, _, err := d.Calendar.List(req)
+		if err != nil {
+			d.Logger.Warn("Dispatcher", zap.String("subscriptionId", sub.ID), zap.Error(err))
+			continue
+		}
+
+		if prev, ok := d.snapshots[sub.ID]; ok {
+			for _, tr := range c.Transitions(prev.events, events, prev.at, now.Unix()) {
+				if sub.Wants(tr) {
+					go d.deliver(ctx, sub, tr)
+				}
+			}
+		}
+		d.snapshots[sub.ID] = snapshot{events: events, at: now.Unix()}
+
+		for _, e := range events {
+			if until := time.Unix(e.RefreshAt, 0).Sub(now); until < wait {
+				wait = until
+			}
+		}
+	}
+
+	for id := range d.snapshots {
+		if !seen[id] {
+			delete(d.snapshots, id)
+		}
+	}
+
+	return max(wait, minWait)
+}
+
+// deliver posts a transition, retrying with exponential backoff, and records
+// every attempt in the delivery log
+func (d *Dispatcher) deliver(ctx context.Context, sub Subscription, tr t.Transition) {
+	delivery := Delivery{
+		ID:             uuid.NewString(),
+		SubscriptionID: sub.ID,
+		Transition:     tr.Kind,
+		EventName:      tr.Event.RawName,
+		Status:         StatusPending,
+		CreatedAt:      time.Now().Unix(),
+	}
+
+	body, err := json.Marshal(Payload{ID: delivery.ID, SubscriptionID: sub.ID, Transition: apiv2.FromTransition(tr), SentAt: delivery.CreatedAt})
+	if err != nil {
+		d.Logger.Error("Dispatcher", zap.Error(err))
+		return
+	}
+
+	backoff := d.Backoff
+	for delivery.Attempts < d.Attempts {
+		delivery.Attempts++
+		delivery.StatusCode, delivery.Error = d.post(ctx, sub, delivery.ID, body)
+		delivery.UpdatedAt = time.Now().Unix()
+
+		switch {
+		case delivery.Error == "":
+			delivery.Status = StatusDelivered
+		case delivery.Attempts == d.Attempts:
+			delivery.Status = StatusFailed
+		}
+		d.record(delivery)
+
+		if delivery.Status != StatusPending {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// post makes a single delivery attempt, it returns the response status and
+// why the attempt failed
+func (d *Dispatcher) post(ctx context.Context, sub Subscription, id string, body []byte) (int, string) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	resp, err := d.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader(IDHeader, id).
+		SetHeader(SignatureHeader, Sign(sub.Secret, timestamp, body)).
+		SetBody(body).
+		Post(sub.URL)
+	if err != nil {
+		d.Logger.Debug("Dispatcher", zap.String("subscriptionId", sub.ID), zap.String("err", redact.Text(err.Error())))
+		return 0, failure(err)
+	}
+	if resp.IsError() {
+		return resp.StatusCode(), fmt.Sprintf("status %d", resp.StatusCode())
+	}
+	return resp.StatusCode(), ""
+}
+
+// failure describes why a request could not be made without the URL or
+// addresses the error of the HTTP client carries
+func failure(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "request failed: canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "request failed: timeout"
+	case errors.As(err, &dnsErr):
+		return "request failed: host not found"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "request failed: connection refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "request failed: connection closed"
+	case errors.As(err, &certErr):
+		return "request failed: certificate not trusted"
+	}
+	return "request failed"
+}
+
+func (d *Dispatcher) record(delivery Delivery) {
+	if err := d.Store.Record(delivery); err != nil {
+		d.Logger.Error("Dispatcher", zap.String("deliveryId", delivery.ID), zap.Error(err))
+	}
+}
+
+// Sign returns the signature header of a payload: the time it was signed and
+// the hex HMAC-SHA256 of the timestamp and body joined by a dot. Receivers
+// recompute the HMAC and reject stale timestamps to prevent replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
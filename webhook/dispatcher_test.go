@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+)
+
+func TestPostKeepsURLOutOfErrors(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	done := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer slow.Close()
+	defer close(done)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		want   string
+	}{
+		{name: "refused", url: closed.URL, want: "request failed: connection refused"},
+		{name: "timeout", url: slow.URL, want: "request failed: timeout"},
+		{name: "unknown host", url: "http://hook.invalid", want: "request failed: host not found"},
+		{name: "error status", url: failing.URL, status: http.StatusBadGateway, want: "status 502"},
+	}
+	d := &Dispatcher{Logger: zap.NewNop(), client: resty.New().SetTimeout(200 * time.Millisecond)}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			url := test.url + "/hooks/s3cret?token=s3cret"
+			status, msg := d.post(context.Background(), Subscription{ID: "sub", URL: url, Secret: "key"}, "delivery", []byte("{}"))
+			if status != test.status || msg != test.want {
+				t.Errorf("post = %d, %q, want %d, %q", status, msg, test.status, test.want)
+			}
+			if strings.Contains(msg, "s3cret") {
+				t.Errorf("post error %q holds the subscription URL", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/envelope"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/jsonfile"
+)
+
+// maxDeliveries is how many deliveries the log keeps, the oldest are dropped first
+const maxDeliveries = 1000
+
+var ErrNotFound = errors.New("subscription not found")
+
+// Store holds the subscriptions and the delivery log. When it has a path both
+// are written to that JSON file on every change and read back on start up.
+// Subscription URLs and secrets are encrypted in the file with keys.
+type Store struct {
+	path   string
+	keys   *envelope.Keyring
+	unlock func() error
+
+	mu            sync.RWMutex
+	subscriptions map[string]Subscription
+	deliveries    []Delivery
+}
+
+// storeFile is the layout of the file a Store persists to
+type storeFile struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+	Deliveries    []Delivery     `json:"deliveries"`
+}
+
+// NewStore creates a store that persists to path, an empty path keeps
+// everything in memory. The store holds the lock of the file until Close, it
+// fails with jsonfile.ErrLocked while another process has the file open.
+// Secrets sealed with another key than the first are sealed again right away,
+// so old keys can be dropped once every server was restarted with a new one.
+func NewStore(path string, keys *envelope.Keyring) (*Store, error) {
+	s := &Store{path: path, keys: keys, subscriptions: map[string]Subscription{}}
+	if path == "" {
+		return s, nil
+	}
+
+	unlock, err := jsonfile.Lock(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.unlock = unlock
+	if err := s.load(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the file of the store and seals stale secrets again
+func (s *Store) load() error {
+	var file storeFile
+	if err := jsonfile.Load(s.path, &file); err != nil {
+		return err
+	}
+	stale := false
+	for _, sub := range file.Subscriptions {
+		if _, err := sub.withSecrets(func(value string) (string, error) {
+			stale = stale || !s.keys.Current(value)
+			return value, nil
+		}); err != nil {
+			return err
+		}
+		opened, err := sub.withSecrets(s.keys.Open)
+		if err != nil {
+			return fmt.Errorf("subscription %s: %w", sub.ID, err)
+		}
+		s.subscriptions[sub.ID] = opened
+	}
+	s.deliveries = file.Deliveries
+
+	if stale {
+		return s.save()
+	}
+	return nil
+}
+
+// Close releases the lock of the file
+func (s *Store) Close() error {
+	if s.unlock == nil {
+		return nil
+	}
+	return s.unlock()
+}
+
+// Add saves a new subscription, assigning its id
+func (s *Store) Add(sub Subscription) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub.ID = uuid.NewString()
+	sub.CreatedAt = time.Now().Unix()
+	s.subscriptions[sub.ID] = sub
+
+	return sub, s.save()
+}
+
+// Remove deletes a subscription, its deliveries stay in the log
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.subscriptions, id)
+
+	return s.save()
+}
+
+// Subscriptions returns every subscription, oldest first
+func (s *Store) Subscriptions() []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subs := make([]Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].CreatedAt != subs[j].CreatedAt {
+			return subs[i].CreatedAt < subs[j].CreatedAt
+		}
+		return subs[i].ID < subs[j].ID
+	})
+
+	return subs
+}
+
+// Record adds a delivery to the log or updates it after another attempt
+func (s *Store) Record(d Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if s.deliveries[i].ID == d.ID {
+			s.deliveries[i] = d
+			return s.save()
+		}
+	}
+
+	s.deliveries = append(s.deliveries, d)
+	if over := len(s.deliveries) - maxDeliveries; over > 0 {
+		s.deliveries = append([]Delivery{}, s.deliveries[over:]...)
+	}
+
+	return s.save()
+}
+
+// Deliveries returns up to limit deliveries, newest first. An empty
+// subscription id returns the deliveries of every subscription.
+func (s *Store) Deliveries(subscriptionID string, limit int) []Delivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := []Delivery{}
+	for i := len(s.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if subscriptionID == "" || s.deliveries[i].SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, s.deliveries[i])
+		}
+	}
+
+	return deliveries
+}
+
+// Reencrypt writes the store again, sealing every secret with the current
+// master key
+func (s *Store) Reencrypt() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save()
+}
+
+// save writes the store to its file, the caller holds the lock
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	file := storeFile{Subscriptions: make([]Subscription, 0, len(s.subscriptions)), Deliveries: s.deliveries}
+	for _, sub := range s.subscriptions {
+		sealed, err := sub.withSecrets(s.keys.Seal)
+		if err != nil {
+			return err
+		}
+		file.Subscriptions = append(file.Subscriptions, sealed)
+	}
+
+	return jsonfile.Save(s.path, file)
+}
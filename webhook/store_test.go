@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/envelope"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/jsonfile"
+	"github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+func masterKey(tb testing.TB, id string) string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		tb.Fatal(err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(b)
+}
+
+func keyring(tb testing.TB, spec string) *envelope.Keyring {
+	keys, err := envelope.Parse(spec)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return keys
+}
+
+func TestNewStoreSealsWithFirstKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	old, next := masterKey(t, "old"), masterKey(t, "new")
+
+	store, err := NewStore(path, keyring(t, old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := Subscription{URL: "https://hooks.example.com/s3cret", Secret: "key", Feed: types.IcsRequest{ICSUrl: "https://example.com/cal.ics"}}
+	if _, err := store.Add(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is held while the store is open
+	if _, err := NewStore(path, keyring(t, next+","+old)); !errors.Is(err, jsonfile.ErrLocked) {
+		t.Fatalf("NewStore of an open file error = %v, want ErrLocked", err)
+	}
+	store.Close()
+
+	// Opening with the new key first seals the file with it straight away
+	store, err = NewStore(path, keyring(t, next+","+old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "enc:v1:old:") || !strings.Contains(string(data), "enc:v1:new:") {
+		t.Errorf("store was not sealed with the new key:\n%s", data)
+	}
+
+	// so the old key can be dropped
+	store, err = NewStore(path, keyring(t, next))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if subs := store.Subscriptions(); len(subs) != 1 || subs[0].URL != sub.URL || subs[0].Secret != sub.Secret {
+		t.Errorf("Subscriptions() = %+v, want the subscription opened with the new key", subs)
+	}
+}
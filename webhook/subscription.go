@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"github.com/quesurifn/ics-calendar-tidbyt-server/pkg/sliceutil"
+	t "github.com/quesurifn/ics-calendar-tidbyt-server/types"
+)
+
+// Kinds are the transitions a subscription can report
+var Kinds = []t.TransitionKind{
+	t.TransitionThreshold,
+	t.TransitionStarted,
+	t.TransitionEnded,
+	t.TransitionRescheduled,
+	t.TransitionCancelled,
+}
+
+// Subscription asks for the transitions of a feed's events to be posted to a URL
+type Subscription struct {
+	ID          string             `json:"id"`
+	URL         string             `json:"url" required:"true" format:"uri" doc:"Where transitions are posted"`
+	Secret      string             `json:"secret,omitempty" required:"true" doc:"Key the payloads are signed with, never returned"`
+	Transitions []t.TransitionKind `json:"transitions" doc:"Transitions to report: threshold, started, ended, rescheduled or cancelled. Defaults to all of them."`
+	Thresholds  []int64            `json:"thresholds" doc:"Seconds before the start that threshold transitions are reported for, any of 600, 300 and 60. Defaults to all of them."`
+	Feed        t.IcsRequest       `json:"feed" required:"true" doc:"The feed to watch, in-progress events are always included"`
+	CreatedAt   int64              `json:"createdAt"`
+}
+
+// Wants reports whether the subscription asked for a transition
+func (s Subscription) Wants(tr t.Transition) bool {
+	if len(s.Transitions) > 0 && !sliceutil.Contains(s.Transitions, tr.Kind) {
+		return false
+	}
+	if tr.Kind == t.TransitionThreshold && len(s.Thresholds) > 0 {
+		return sliceutil.Contains(s.Thresholds, tr.Threshold)
+	}
+	return true
+}
+
+// Redacted returns the subscription without its secret
+func (s Subscription) Redacted() Subscription {
+	s.Secret = ""
+	return s
+}
+
+// withSecrets returns the subscription with f applied to its URLs and secret,
+// the store seals them with it before writing and opens them after reading
+func (s Subscription) withSecrets(f func(string) (string, error)) (Subscription, error) {
+	for _, field := range []*string{&s.URL, &s.Secret, &s.Feed.ICSUrl} {
+		value, err := f(*field)
+		if err != nil {
+			return Subscription{}, err
+		}
+		*field = value
+	}
+	return s, nil
+}